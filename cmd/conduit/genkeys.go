@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/askerdev/realworld-clone-go/pkg/simplejwt"
+)
+
+func genkeys(_ context.Context, args []string) error {
+	fs := flag.NewFlagSet("conduit genkeys", flag.ContinueOnError)
+	out := fs.String("out", "keys/auth.ed", "path of the private key, the public key gets a .pub suffix")
+	force := fs.Bool("force", false, "overwrite existing keys")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return errStop
+		}
+		return err
+	}
+
+	privatePath := *out
+	publicPath := *out + ".pub"
+
+	if !*force {
+		for _, path := range []string{privatePath, publicPath} {
+			if _, err := os.Stat(path); err == nil {
+				return fmt.Errorf("%s already exists, use -force to replace it", path)
+			}
+		}
+	}
+
+	privatePEM, publicPEM, err := simplejwt.GenerateKeyPair()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(privatePath), 0o700); err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(privatePath, privatePEM, 0o600); err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(publicPath, publicPEM, 0o644); err != nil {
+		return err
+	}
+
+	fmt.Printf("wrote %s and %s\n", privatePath, publicPath)
+
+	return nil
+}
@@ -0,0 +1,146 @@
+// Command conduit runs the Conduit API server and its maintenance tasks.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"sort"
+	"syscall"
+
+	"github.com/askerdev/realworld-clone-go/internal/config"
+	"github.com/askerdev/realworld-clone-go/internal/domain/vo"
+	"github.com/askerdev/realworld-clone-go/internal/tracing"
+	_ "github.com/jackc/pgx/stdlib"
+	"github.com/jmoiron/sqlx"
+)
+
+type command struct {
+	usage string
+	run   func(ctx context.Context, args []string) error
+}
+
+var commands = map[string]command{
+	"serve":   {"run the API server", serve},
+	"migrate": {"apply, revert or list database migrations (up, down, status)", migrateCommand},
+	"genkeys": {"write a new Ed25519 signing key pair", genkeys},
+	"user":    {"manage accounts (create, promote, reset-password)", user},
+	"reindex": {"rebuild table indexes and prune unused tags", reindex},
+	"recount": {"recompute articles' favorite counts", recount},
+}
+
+// errStop ends a command early without it having failed, e.g. after -help
+// or -print-config.
+var errStop = errors.New("stop")
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: conduit <command> [flags]")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "commands:")
+
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", name, commands[name].usage)
+	}
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	cmd, ok := commands[os.Args[1]]
+	if !ok {
+		if os.Args[1] != "help" && os.Args[1] != "-h" && os.Args[1] != "-help" {
+			fmt.Fprintf(os.Stderr, "conduit: unknown command %q\n\n", os.Args[1])
+		}
+		usage()
+		os.Exit(2)
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	if err := cmd.run(ctx, os.Args[2:]); err != nil && !errors.Is(err, errStop) {
+		slog.Error(err.Error())
+		cancel()
+		os.Exit(1)
+	}
+}
+
+// loadConfig parses the config flags of a command, next to the flags define
+// adds. It prints the config and returns errStop for -print-config.
+func loadConfig(name string, args []string, define func(fs *flag.FlagSet)) (*config.Config, *flag.FlagSet, error) {
+	fs := flag.NewFlagSet("conduit "+name, flag.ContinueOnError)
+	if define != nil {
+		define(fs)
+	}
+
+	cfg, err := config.Load(fs, args, os.LookupEnv)
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil, nil, errStop
+		}
+		return nil, nil, fmt.Errorf("invalid config: %w", err)
+	}
+
+	if cfg.PrintConfig {
+		if err := cfg.Write(os.Stdout); err != nil {
+			return nil, nil, err
+		}
+		return nil, nil, errStop
+	}
+
+	return cfg, fs, nil
+}
+
+func newPasswordHashing(cfg config.Password) (*vo.PasswordHashing, error) {
+	argon2id := vo.DefaultArgon2idHasher()
+	argon2id.Memory = uint32(cfg.Argon2Memory)
+	argon2id.Time = uint32(cfg.Argon2Time)
+	argon2id.Threads = uint8(cfg.Argon2Threads)
+
+	bcryptHasher := vo.BcryptHasher{Cost: cfg.BcryptCost}
+
+	switch cfg.Hash {
+	case "argon2id":
+		return &vo.PasswordHashing{Default: argon2id, Legacy: []vo.PasswordHasher{bcryptHasher}}, nil
+	case "bcrypt":
+		return &vo.PasswordHashing{Default: bcryptHasher, Legacy: []vo.PasswordHasher{argon2id}}, nil
+	default:
+		return nil, fmt.Errorf("unknown password hash %q", cfg.Hash)
+	}
+}
+
+func connectDB(ctx context.Context, cfg config.Database) (*sqlx.DB, error) {
+	sqlDB, err := tracing.OpenDB("pgx", cfg.DSN.Reveal())
+	if err != nil {
+		return nil, err
+	}
+	db := sqlx.NewDb(sqlDB, "pgx")
+
+	db.SetMaxOpenConns(cfg.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.MaxIdleConns)
+	db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+
+	ctx, cancel := context.WithTimeout(ctx, cfg.ConnectTimeout)
+	defer cancel()
+
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return db, nil
+}
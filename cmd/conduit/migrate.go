@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/askerdev/realworld-clone-go/internal/config"
+	"github.com/askerdev/realworld-clone-go/internal/migrate"
+	"github.com/askerdev/realworld-clone-go/migrations"
+	"github.com/jmoiron/sqlx"
+)
+
+// ensureSchema brings the schema up to date on startup, or checks that it
+// is, depending on the config. The migrator is returned for readiness
+// checks.
+func ensureSchema(ctx context.Context, db *sqlx.DB, cfg config.Database) (*migrate.Migrator, error) {
+	m, err := migrate.New(db, migrations.FS)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.AutoMigrate {
+		applied, err := m.Up(ctx)
+		for _, migration := range applied {
+			slog.Info("applied migration", slog.Uint64("version", migration.Version), slog.String("name", migration.Name))
+		}
+		return m, err
+	}
+
+	pending, err := m.Pending(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(pending) == 0 {
+		return m, nil
+	}
+
+	if cfg.RequireLatestSchema {
+		return nil, fmt.Errorf(
+			"schema is %d migrations behind, run conduit migrate up (first pending: %d_%s)",
+			len(pending), pending[0].Version, pending[0].Name,
+		)
+	}
+
+	slog.Warn("schema is behind, run conduit migrate up", slog.Int("pending", len(pending)))
+
+	return m, nil
+}
+
+func migrateUsage() error {
+	return fmt.Errorf("usage: conduit migrate up|down|status [flags]")
+}
+
+func migrateCommand(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return migrateUsage()
+	}
+
+	action := args[0]
+	var steps int
+	define := func(fs *flag.FlagSet) {
+		if action == "down" {
+			fs.IntVar(&steps, "steps", 1, "number of migrations to revert")
+		}
+	}
+
+	switch action {
+	case "up", "down", "status":
+		break
+	default:
+		return migrateUsage()
+	}
+
+	cfg, fs, err := loadConfig("migrate "+action, args[1:], define)
+	if err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return migrateUsage()
+	}
+
+	db, err := connectDB(ctx, cfg.Database)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	m, err := migrate.New(db, migrations.FS)
+	if err != nil {
+		return err
+	}
+
+	switch action {
+	case "up":
+		applied, err := m.Up(ctx)
+		for _, migration := range applied {
+			fmt.Printf("applied %d_%s\n", migration.Version, migration.Name)
+		}
+		if err != nil {
+			return err
+		}
+		if len(applied) == 0 {
+			fmt.Println("schema is up to date")
+		}
+		break
+	case "down":
+		if steps < 1 {
+			return fmt.Errorf("-steps must be at least 1")
+		}
+		reverted, err := m.Down(ctx, steps)
+		for _, migration := range reverted {
+			fmt.Printf("reverted %d_%s\n", migration.Version, migration.Name)
+		}
+		if err != nil {
+			return err
+		}
+		break
+	case "status":
+		statuses, err := m.Status(ctx)
+		if err != nil {
+			return err
+		}
+
+		tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "VERSION\tNAME\tAPPLIED")
+		for _, status := range statuses {
+			applied := "pending"
+			if status.AppliedAt != nil {
+				applied = status.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(tw, "%d\t%s\t%s\n", status.Version, status.Name, applied)
+		}
+		return tw.Flush()
+	}
+
+	return nil
+}
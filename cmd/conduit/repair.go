@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/askerdev/realworld-clone-go/internal/postgres"
+)
+
+func reindex(ctx context.Context, args []string) error {
+	cfg, fs, err := loadConfig("reindex", args, nil)
+	if err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("reindex takes no arguments")
+	}
+
+	db, err := connectDB(ctx, cfg.Database)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	storage := postgres.NewStorage(db)
+
+	pruned, err := storage.PruneTags(ctx)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("pruned %d unused tags\n", pruned)
+
+	if err := storage.Reindex(ctx); err != nil {
+		return err
+	}
+	fmt.Println("rebuilt indexes")
+
+	return nil
+}
+
+func recount(ctx context.Context, args []string) error {
+	cfg, fs, err := loadConfig("recount", args, nil)
+	if err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("recount takes no arguments")
+	}
+
+	db, err := connectDB(ctx, cfg.Database)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	fixed, err := postgres.NewStorage(db).RecountFavorites(ctx)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("fixed favorite counts of %d articles\n", fixed)
+
+	return nil
+}
@@ -0,0 +1,309 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/askerdev/realworld-clone-go/internal/config"
+	"github.com/askerdev/realworld-clone-go/internal/cors"
+	"github.com/askerdev/realworld-clone-go/internal/domain/vo"
+	"github.com/askerdev/realworld-clone-go/internal/handler"
+	"github.com/askerdev/realworld-clone-go/internal/mail"
+	"github.com/askerdev/realworld-clone-go/internal/mem"
+	"github.com/askerdev/realworld-clone-go/internal/metrics"
+	"github.com/askerdev/realworld-clone-go/internal/postgres"
+	"github.com/askerdev/realworld-clone-go/internal/tracing"
+	"github.com/askerdev/realworld-clone-go/pkg/ratelimit"
+	"github.com/askerdev/realworld-clone-go/pkg/realip"
+	"github.com/askerdev/realworld-clone-go/pkg/simplejwt"
+	"github.com/jmoiron/sqlx"
+)
+
+type sessionStore interface {
+	simplejwt.Cache
+	RunEviction(ctx context.Context, interval time.Duration)
+}
+
+type refreshStore interface {
+	simplejwt.RefreshStore
+	RunEviction(ctx context.Context, interval time.Duration)
+}
+
+func newSessionStores(kind string, db *sqlx.DB, ttl time.Duration) (sessionStore, refreshStore, error) {
+	switch kind {
+	case "memory":
+		return mem.NewJWTCache(ttl), mem.NewRefreshStore(), nil
+	case "postgres":
+		return postgres.NewJWTCache(db, ttl), postgres.NewRefreshStore(db), nil
+	default:
+		return nil, nil, fmt.Errorf("unknown session store %q", kind)
+	}
+}
+
+type rateLimitStore interface {
+	ratelimit.Store
+	RunEviction(ctx context.Context, interval time.Duration)
+}
+
+func newRateLimitStore(kind string, db *sqlx.DB) (rateLimitStore, error) {
+	switch kind {
+	case "memory":
+		return mem.NewRateLimitStore(), nil
+	case "postgres":
+		return postgres.NewRateLimitStore(db), nil
+	default:
+		return nil, fmt.Errorf("unknown rate limit store %q", kind)
+	}
+}
+
+func rate(r config.Rate) ratelimit.Rate {
+	return ratelimit.Rate{Limit: r.Limit, Period: r.Period}
+}
+
+func newMailer(cfg config.Mail) (mail.Mailer, error) {
+	if cfg.SMTPAddr != "" {
+		return mail.NewSMTP(mail.SMTPConfig{
+			Addr:     cfg.SMTPAddr,
+			Username: cfg.SMTPUsername,
+			Password: cfg.SMTPPassword.Reveal(),
+			From:     cfg.From,
+		}), nil
+	}
+
+	return mail.NewOutbox(cfg.From, cfg.Outbox)
+}
+
+// newAdminServer serves /metrics on its own listener, kept off the public
+// API.
+func newAdminServer(cfg config.HTTP, m *metrics.Metrics) *http.Server {
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", m.Handler())
+
+	return &http.Server{
+		Addr:              cfg.AdminAddr,
+		Handler:           mux,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		ReadTimeout:       cfg.ReadTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+	}
+}
+
+func serve(ctx context.Context, args []string) error {
+	cfg, fs, err := loadConfig("serve", args, nil)
+	if err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("serve takes no arguments")
+	}
+
+	if cfg.JWT.SigningKey == "" {
+		return errors.New("jwt.signing-key is required to serve")
+	}
+
+	passwordHashing, err := newPasswordHashing(cfg.Password)
+	if err != nil {
+		return err
+	}
+	vo.SetPasswordHashing(passwordHashing)
+
+	jwtOptions := simplejwt.Options{
+		Issuer:   cfg.JWT.Issuer,
+		Audience: cfg.JWT.Audience,
+		TTL:      cfg.JWT.TTL,
+		Leeway:   cfg.JWT.Leeway,
+		Schemes:  cfg.JWT.Schemes,
+	}
+
+	keyset, err := simplejwt.LoadKeyset(cfg.JWT.SigningKey, cfg.JWT.PublicKeys...)
+	if err != nil {
+		return err
+	}
+
+	shutdownTracing, err := tracing.Setup(ctx, tracing.Options{
+		Exporter:    cfg.Tracing.Exporter,
+		File:        cfg.Tracing.File,
+		Endpoint:    cfg.Tracing.Endpoint,
+		Insecure:    cfg.Tracing.Insecure,
+		SampleRatio: cfg.Tracing.SampleRatio,
+		ServiceName: cfg.Tracing.ServiceName,
+	})
+	if err != nil {
+		return err
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.HTTP.ShutdownTimeout)
+		defer cancel()
+
+		if err := shutdownTracing(ctx); err != nil {
+			slog.Error(err.Error())
+		}
+	}()
+
+	db, err := connectDB(ctx, cfg.Database)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	migrator, err := ensureSchema(ctx, db, cfg.Database)
+	if err != nil {
+		return err
+	}
+
+	jwtCache, refreshStore, err := newSessionStores(cfg.JWT.SessionStore, db, jwtOptions.TTL+jwtOptions.Leeway)
+	if err != nil {
+		return err
+	}
+
+	issuer, err := simplejwt.NewIssuer(keyset, jwtCache, refreshStore, jwtOptions)
+	if err != nil {
+		return err
+	}
+
+	validator, err := simplejwt.NewValidator(keyset, jwtCache, jwtOptions)
+	if err != nil {
+		return err
+	}
+
+	mailer, err := newMailer(cfg.Mail)
+	if err != nil {
+		return err
+	}
+
+	m := metrics.New(db.DB)
+
+	realIP, err := realip.New(cfg.HTTP.TrustedProxies...)
+	if err != nil {
+		return err
+	}
+
+	rateLimits, err := newRateLimitStore(cfg.RateLimit.Store, db)
+	if err != nil {
+		return err
+	}
+
+	h := handler.New(
+		db,
+		issuer,
+		validator,
+		mailer,
+		handler.Options{
+			AppURL:               strings.TrimSuffix(cfg.App.URL, "/"),
+			RequireVerifiedEmail: cfg.App.RequireVerifiedEmail,
+			Metrics:              m,
+			Migrator:             migrator,
+			RealIP:               realIP,
+			RateLimitStore:       rateLimits,
+			RateLimits: handler.RateLimits{
+				Public:      rate(cfg.RateLimit.Public),
+				Credentials: rate(cfg.RateLimit.Credentials),
+				Auth:        rate(cfg.RateLimit.Auth),
+				User:        rate(cfg.RateLimit.User),
+				Create:      rate(cfg.RateLimit.Create),
+			},
+		},
+	)
+
+	srv := &http.Server{
+		Addr: cfg.HTTP.Addr,
+		Handler: cors.Handler(cors.Options{
+			AllowedOrigins:   cfg.CORS.AllowedOrigins,
+			AllowedMethods:   cfg.CORS.AllowedMethods,
+			AllowedHeaders:   cfg.CORS.AllowedHeaders,
+			ExposedHeaders:   cfg.CORS.ExposedHeaders,
+			AllowCredentials: cfg.CORS.AllowCredentials,
+			MaxAge:           cfg.CORS.MaxAge,
+		}, h),
+		ReadHeaderTimeout: cfg.HTTP.ReadHeaderTimeout,
+		ReadTimeout:       cfg.HTTP.ReadTimeout,
+		WriteTimeout:      cfg.HTTP.WriteTimeout,
+		IdleTimeout:       cfg.HTTP.IdleTimeout,
+	}
+
+	servers := []*http.Server{srv}
+	if cfg.HTTP.AdminAddr != "" {
+		servers = append(servers, newAdminServer(cfg.HTTP, m))
+	}
+
+	wg := &sync.WaitGroup{}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		jwtCache.RunEviction(ctx, time.Minute)
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		refreshStore.RunEviction(ctx, time.Hour)
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		rateLimits.RunEviction(ctx, time.Minute)
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		h.RunEviction(ctx, time.Hour)
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		h.RunUserCacheEviction(ctx, time.Minute)
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		<-ctx.Done()
+
+		slog.Warn("Shutting down server!")
+
+		// Fail readiness first and give load balancers time to notice
+		// before connections start being refused.
+		h.Drain()
+		time.Sleep(cfg.HTTP.DrainDelay)
+
+		shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), cfg.HTTP.ShutdownTimeout)
+		defer shutdownCancel()
+
+		for _, srv := range servers {
+			if err := srv.Shutdown(shutdownCtx); err != nil {
+				slog.Error(err.Error())
+			}
+		}
+	}()
+
+	for _, srv := range servers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				slog.Error(err.Error())
+				return
+			}
+		}()
+
+		slog.Info("Listening on " + srv.Addr)
+	}
+
+	wg.Wait()
+
+	slog.Warn("Server stopped!")
+
+	return nil
+}
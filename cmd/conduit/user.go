@@ -0,0 +1,256 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/askerdev/realworld-clone-go/internal/domain/entity"
+	"github.com/askerdev/realworld-clone-go/internal/domain/vo"
+	"github.com/askerdev/realworld-clone-go/internal/postgres"
+	"github.com/askerdev/realworld-clone-go/pkg/simplejwt"
+	"github.com/guregu/null/v5"
+)
+
+func userUsage() error {
+	return fmt.Errorf("usage: conduit user create|promote|reset-password [flags]")
+}
+
+func user(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return userUsage()
+	}
+
+	switch args[0] {
+	case "create":
+		return userCreate(ctx, args[1:])
+	case "promote":
+		return userPromote(ctx, args[1:])
+	case "reset-password":
+		return userResetPassword(ctx, args[1:])
+	default:
+		return userUsage()
+	}
+}
+
+// readPassword takes the password from the flag, or else from the first
+// line of stdin so it stays out of the shell history.
+func readPassword(flagValue string) (vo.Password, error) {
+	value := flagValue
+	if value == "" {
+		fmt.Fprint(os.Stderr, "password: ")
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			return "", fmt.Errorf("reading password: %w", err)
+		}
+		value = strings.TrimRight(line, "\r\n")
+	}
+
+	return vo.NewPassword(value)
+}
+
+func findUser(ctx context.Context, storage *postgres.Storage, login string) (*entity.User, error) {
+	if login == "" {
+		return nil, errors.New("-user is required")
+	}
+
+	u, err := storage.SelectUserByLogin(ctx, login)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("no user with email or username %q", login)
+		}
+		return nil, err
+	}
+
+	return u, nil
+}
+
+func userCreate(ctx context.Context, args []string) error {
+	var email, username, password, role string
+	var verified bool
+	cfg, _, err := loadConfig("user create", args, func(fs *flag.FlagSet) {
+		fs.StringVar(&email, "email", "", "email of the new user")
+		fs.StringVar(&username, "username", "", "username of the new user")
+		fs.StringVar(&password, "password", "", "password, read from stdin when empty")
+		fs.StringVar(&role, "role", string(entity.RoleUser), "role: user, moderator or admin")
+		fs.BoolVar(&verified, "verified", true, "mark the email as verified")
+	})
+	if err != nil {
+		return err
+	}
+
+	validEmail, err := vo.NewEmail(email)
+	if err != nil {
+		return fmt.Errorf("email: %w", err)
+	}
+
+	validUsername, err := vo.NewUsername(username)
+	if err != nil {
+		return fmt.Errorf("username: %w", err)
+	}
+
+	validRole, err := entity.ParseRole(role)
+	if err != nil {
+		return err
+	}
+
+	validPassword, err := readPassword(password)
+	if err != nil {
+		return fmt.Errorf("password: %w", err)
+	}
+
+	passwordHashing, err := newPasswordHashing(cfg.Password)
+	if err != nil {
+		return err
+	}
+	vo.SetPasswordHashing(passwordHashing)
+
+	passHash, err := validPassword.Hash()
+	if err != nil {
+		return err
+	}
+
+	db, err := connectDB(ctx, cfg.Database)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	storage := postgres.NewStorage(db)
+
+	u, err := storage.InsertUser(ctx, string(validEmail), string(validUsername), passHash)
+	if err != nil {
+		if errors.Is(err, postgres.ErrUniqueConstraint) {
+			return errors.New("email or username already exists")
+		}
+		return err
+	}
+
+	if validRole != entity.RoleUser {
+		if err := storage.SetUserRole(ctx, u.ID, validRole); err != nil {
+			return err
+		}
+	}
+
+	if verified {
+		if err := storage.MarkEmailVerified(ctx, u.ID); err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("created %s (id %d) with role %s\n", u.Username, u.ID, validRole)
+
+	return nil
+}
+
+func userPromote(ctx context.Context, args []string) error {
+	var login, role string
+	cfg, _, err := loadConfig("user promote", args, func(fs *flag.FlagSet) {
+		fs.StringVar(&login, "user", "", "email or username")
+		fs.StringVar(&role, "role", string(entity.RoleAdmin), "new role: user, moderator or admin")
+	})
+	if err != nil {
+		return err
+	}
+
+	validRole, err := entity.ParseRole(role)
+	if err != nil {
+		return err
+	}
+
+	db, err := connectDB(ctx, cfg.Database)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	storage := postgres.NewStorage(db)
+
+	u, err := findUser(ctx, storage, login)
+	if err != nil {
+		return err
+	}
+
+	if err := storage.SetUserRole(ctx, u.ID, validRole); err != nil {
+		return err
+	}
+
+	fmt.Printf("%s is now %s (was %s)\n", u.Username, validRole, u.Role)
+
+	return nil
+}
+
+func userResetPassword(ctx context.Context, args []string) error {
+	var login, password string
+	cfg, _, err := loadConfig("user reset-password", args, func(fs *flag.FlagSet) {
+		fs.StringVar(&login, "user", "", "email or username")
+		fs.StringVar(&password, "password", "", "new password, read from stdin when empty")
+	})
+	if err != nil {
+		return err
+	}
+
+	db, err := connectDB(ctx, cfg.Database)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	storage := postgres.NewStorage(db)
+
+	u, err := findUser(ctx, storage, login)
+	if err != nil {
+		return err
+	}
+
+	validPassword, err := readPassword(password)
+	if err != nil {
+		return fmt.Errorf("password: %w", err)
+	}
+
+	passwordHashing, err := newPasswordHashing(cfg.Password)
+	if err != nil {
+		return err
+	}
+	vo.SetPasswordHashing(passwordHashing)
+
+	passHash, err := validPassword.Hash()
+	if err != nil {
+		return err
+	}
+
+	_, err = storage.UpdateUser(ctx, &postgres.UpdateUserParams{
+		ID:       u.ID,
+		Password: null.StringFrom(passHash),
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("password of %s has been reset\n", u.Username)
+
+	// sessions can only be revoked here when the server keeps them in
+	// postgres; the memory store lives inside the server process
+	if cfg.JWT.SessionStore != "postgres" {
+		fmt.Println("sessions are kept in memory by the server, existing tokens stay valid until they expire")
+		return nil
+	}
+
+	err = simplejwt.RevokeSessions(
+		postgres.NewJWTCache(db, cfg.JWT.TTL+cfg.JWT.Leeway),
+		postgres.NewRefreshStore(db),
+		u.ID,
+	)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("revoked all sessions")
+
+	return nil
+}
@@ -0,0 +1,296 @@
+// Package config loads the service configuration. Values come from the
+// defaults, then a YAML file, then CONDUIT_* environment variables, then
+// command-line flags, each overriding the one before.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"net/url"
+	"slices"
+	"time"
+)
+
+type Config struct {
+	HTTP      HTTP      `yaml:"http"`
+	Database  Database  `yaml:"database"`
+	JWT       JWT       `yaml:"jwt"`
+	Password  Password  `yaml:"password"`
+	Mail      Mail      `yaml:"mail"`
+	App       App       `yaml:"app"`
+	CORS      CORS      `yaml:"cors"`
+	Tracing   Tracing   `yaml:"tracing"`
+	RateLimit RateLimit `yaml:"rate-limit"`
+
+	// PrintConfig asks the caller to print the effective config and exit.
+	PrintConfig bool `yaml:"-"`
+}
+
+type HTTP struct {
+	Addr              string        `yaml:"addr"`
+	ReadHeaderTimeout time.Duration `yaml:"read-header-timeout"`
+	ReadTimeout       time.Duration `yaml:"read-timeout"`
+	WriteTimeout      time.Duration `yaml:"write-timeout"`
+	IdleTimeout       time.Duration `yaml:"idle-timeout"`
+	ShutdownTimeout   time.Duration `yaml:"shutdown-timeout"`
+	// DrainDelay is how long /readyz fails before shutdown starts.
+	DrainDelay time.Duration `yaml:"drain-delay"`
+	// AdminAddr is where the admin listener serves /metrics. Empty turns it
+	// off. Keep it off the public network.
+	AdminAddr string `yaml:"admin-addr"`
+	// TrustedProxies are the CIDRs of proxies whose X-Forwarded-For is
+	// believed when finding the client IP.
+	TrustedProxies List `yaml:"trusted-proxies"`
+}
+
+type Database struct {
+	DSN             Secret        `yaml:"dsn"`
+	MaxOpenConns    int           `yaml:"max-open-conns"`
+	MaxIdleConns    int           `yaml:"max-idle-conns"`
+	ConnMaxLifetime time.Duration `yaml:"conn-max-lifetime"`
+	ConnMaxIdleTime time.Duration `yaml:"conn-max-idle-time"`
+	ConnectTimeout  time.Duration `yaml:"connect-timeout"`
+	// AutoMigrate applies pending migrations when the server starts.
+	AutoMigrate bool `yaml:"auto-migrate"`
+	// RequireLatestSchema stops the server from starting while migrations
+	// are pending, instead of only warning. It matters when AutoMigrate is
+	// off.
+	RequireLatestSchema bool `yaml:"require-latest-schema"`
+}
+
+type JWT struct {
+	Issuer   string        `yaml:"issuer"`
+	Audience string        `yaml:"audience"`
+	TTL      time.Duration `yaml:"ttl"`
+	Leeway   time.Duration `yaml:"leeway"`
+	Schemes  List          `yaml:"schemes"`
+	// SigningKey is the path of the Ed25519 private key PEM. Only serving
+	// requires it.
+	SigningKey string `yaml:"signing-key"`
+	// PublicKeys are paths of extra verification keys, e.g. retired
+	// signing keys whose tokens haven't expired yet.
+	PublicKeys   List   `yaml:"public-keys"`
+	SessionStore string `yaml:"session-store"`
+}
+
+type Password struct {
+	Hash          string `yaml:"hash"`
+	Argon2Memory  uint   `yaml:"argon2-memory"`
+	Argon2Time    uint   `yaml:"argon2-time"`
+	Argon2Threads uint   `yaml:"argon2-threads"`
+	BcryptCost    int    `yaml:"bcrypt-cost"`
+}
+
+type Mail struct {
+	From         string `yaml:"from"`
+	Outbox       string `yaml:"outbox"`
+	SMTPAddr     string `yaml:"smtp-addr"`
+	SMTPUsername string `yaml:"smtp-username"`
+	SMTPPassword Secret `yaml:"smtp-password"`
+}
+
+type App struct {
+	URL                  string `yaml:"url"`
+	RequireVerifiedEmail bool   `yaml:"require-verified-email"`
+}
+
+type CORS struct {
+	AllowedOrigins   List          `yaml:"allowed-origins"`
+	AllowedMethods   List          `yaml:"allowed-methods"`
+	AllowedHeaders   List          `yaml:"allowed-headers"`
+	ExposedHeaders   List          `yaml:"exposed-headers"`
+	AllowCredentials bool          `yaml:"allow-credentials"`
+	MaxAge           time.Duration `yaml:"max-age"`
+}
+
+// RateLimit holds a rate per group of routes. Anonymous requests are
+// counted per client IP, authenticated ones per user.
+type RateLimit struct {
+	// Store is memory, per replica, or postgres, shared by replicas.
+	Store string `yaml:"store"`
+	// Public covers reads open to anonymous users.
+	Public Rate `yaml:"public"`
+	// Credentials covers sign-up, login, token refresh and password reset,
+	// always per client IP.
+	Credentials Rate `yaml:"credentials"`
+	// Auth covers every route that accepts credentials, per client IP and
+	// before they are checked, so invalid tokens are limited too.
+	Auth Rate `yaml:"auth"`
+	// User covers every authenticated route.
+	User Rate `yaml:"user"`
+	// Create additionally covers creating articles and comments.
+	Create Rate `yaml:"create"`
+}
+
+type Tracing struct {
+	// Exporter is none, stdout, file or otlp.
+	Exporter string `yaml:"exporter"`
+	// File is where the file exporter appends spans as JSON lines.
+	File string `yaml:"file"`
+	// Endpoint is the OTLP/HTTP collector host:port; when empty the
+	// standard OTEL_EXPORTER_OTLP_* variables apply.
+	Endpoint    string  `yaml:"endpoint"`
+	Insecure    bool    `yaml:"insecure"`
+	SampleRatio float64 `yaml:"sample-ratio"`
+	ServiceName string  `yaml:"service-name"`
+}
+
+func Default() *Config {
+	return &Config{
+		HTTP: HTTP{
+			Addr:              "localhost:8080",
+			ReadHeaderTimeout: 5 * time.Second,
+			ReadTimeout:       15 * time.Second,
+			WriteTimeout:      15 * time.Second,
+			IdleTimeout:       time.Minute,
+			ShutdownTimeout:   10 * time.Second,
+			DrainDelay:        5 * time.Second,
+			AdminAddr:         "localhost:9090",
+		},
+		Database: Database{
+			MaxOpenConns:    25,
+			MaxIdleConns:    5,
+			ConnMaxLifetime: 30 * time.Minute,
+			ConnMaxIdleTime: 5 * time.Minute,
+			ConnectTimeout:  5 * time.Second,
+			AutoMigrate:     true,
+		},
+		JWT: JWT{
+			Issuer:       "http://localhost:8080",
+			Audience:     "api",
+			TTL:          15 * time.Minute,
+			Leeway:       30 * time.Second,
+			Schemes:      List{"Token", "Bearer"},
+			SessionStore: "memory",
+		},
+		Password: Password{
+			Hash:          "argon2id",
+			Argon2Memory:  64 * 1024,
+			Argon2Time:    3,
+			Argon2Threads: 2,
+			BcryptCost:    13,
+		},
+		Mail: Mail{
+			From: "Conduit <no-reply@localhost>",
+		},
+		App: App{
+			URL: "http://localhost:3000",
+		},
+		CORS: CORS{
+			AllowedMethods: List{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+			AllowedHeaders: List{"Authorization", "Content-Type", "X-Request-ID", "traceparent", "tracestate"},
+			ExposedHeaders: List{"X-Request-ID", "Retry-After", "RateLimit-Policy", "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset"},
+			MaxAge:         10 * time.Minute,
+		},
+		RateLimit: RateLimit{
+			Store:       "memory",
+			Public:      Rate{Limit: 300, Period: time.Minute},
+			Credentials: Rate{Limit: 20, Period: time.Minute},
+			Auth:        Rate{Limit: 600, Period: time.Minute},
+			User:        Rate{Limit: 300, Period: time.Minute},
+			Create:      Rate{Limit: 20, Period: time.Minute},
+		},
+		Tracing: Tracing{
+			Exporter:    "none",
+			SampleRatio: 1,
+			ServiceName: "conduit",
+		},
+	}
+}
+
+// Validate reports every problem with the config at once.
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(c.HTTP.Addr != "", "http.addr is required")
+	check(c.HTTP.ReadHeaderTimeout >= 0, "http.read-header-timeout must not be negative")
+	check(c.HTTP.ReadTimeout >= 0, "http.read-timeout must not be negative")
+	check(c.HTTP.WriteTimeout >= 0, "http.write-timeout must not be negative")
+	check(c.HTTP.IdleTimeout >= 0, "http.idle-timeout must not be negative")
+	check(c.HTTP.ShutdownTimeout > 0, "http.shutdown-timeout must be positive")
+	check(c.HTTP.DrainDelay >= 0, "http.drain-delay must not be negative")
+	check(c.HTTP.AdminAddr != c.HTTP.Addr, "http.admin-addr must differ from http.addr")
+
+	check(c.Database.DSN != "", "database.dsn is required")
+	check(c.Database.MaxOpenConns >= 0, "database.max-open-conns must not be negative")
+	check(c.Database.MaxIdleConns >= 0, "database.max-idle-conns must not be negative")
+	check(
+		c.Database.MaxOpenConns == 0 || c.Database.MaxIdleConns <= c.Database.MaxOpenConns,
+		"database.max-idle-conns must not exceed database.max-open-conns",
+	)
+	check(c.Database.ConnMaxLifetime >= 0, "database.conn-max-lifetime must not be negative")
+	check(c.Database.ConnMaxIdleTime >= 0, "database.conn-max-idle-time must not be negative")
+	check(c.Database.ConnectTimeout > 0, "database.connect-timeout must be positive")
+
+	check(c.JWT.Issuer != "", "jwt.issuer is required")
+	check(c.JWT.Audience != "", "jwt.audience is required")
+	check(c.JWT.TTL > 0, "jwt.ttl must be positive")
+	check(c.JWT.Leeway >= 0, "jwt.leeway must not be negative")
+	check(len(c.JWT.Schemes) > 0, "jwt.schemes must not be empty")
+	check(
+		slices.Contains([]string{"memory", "postgres"}, c.JWT.SessionStore),
+		"jwt.session-store must be memory or postgres, got %q", c.JWT.SessionStore,
+	)
+
+	check(
+		slices.Contains([]string{"argon2id", "bcrypt"}, c.Password.Hash),
+		"password.hash must be argon2id or bcrypt, got %q", c.Password.Hash,
+	)
+	check(c.Password.Argon2Memory >= 8*c.Password.Argon2Threads, "password.argon2-memory must be at least 8 KiB per thread")
+	check(c.Password.Argon2Time > 0, "password.argon2-time must be positive")
+	check(
+		c.Password.Argon2Threads > 0 && c.Password.Argon2Threads <= 255,
+		"password.argon2-threads must be between 1 and 255",
+	)
+	check(c.Password.BcryptCost >= 10 && c.Password.BcryptCost <= 31, "password.bcrypt-cost must be between 10 and 31")
+
+	check(c.Mail.From != "", "mail.from is required")
+	check(validURL(c.App.URL), "app.url must be an absolute URL, got %q", c.App.URL)
+
+	for _, origin := range c.CORS.AllowedOrigins {
+		check(origin == "*" || validURL(origin), "cors.allowed-origins: %q is not * or an origin", origin)
+	}
+	check(
+		!c.CORS.AllowCredentials || !slices.Contains(c.CORS.AllowedOrigins, "*"),
+		"cors.allow-credentials can't be combined with the * origin",
+	)
+	check(c.CORS.MaxAge >= 0, "cors.max-age must not be negative")
+
+	for _, proxy := range c.HTTP.TrustedProxies {
+		check(validAddrOrPrefix(proxy), "http.trusted-proxies: %q is not an address or CIDR", proxy)
+	}
+
+	check(
+		slices.Contains([]string{"memory", "postgres"}, c.RateLimit.Store),
+		"rate-limit.store must be memory or postgres, got %q", c.RateLimit.Store,
+	)
+
+	check(
+		slices.Contains([]string{"none", "stdout", "file", "otlp"}, c.Tracing.Exporter),
+		"tracing.exporter must be none, stdout, file or otlp, got %q", c.Tracing.Exporter,
+	)
+	check(c.Tracing.Exporter != "file" || c.Tracing.File != "", "tracing.file is required by the file exporter")
+	check(c.Tracing.SampleRatio >= 0 && c.Tracing.SampleRatio <= 1, "tracing.sample-ratio must be between 0 and 1")
+	check(c.Tracing.ServiceName != "", "tracing.service-name is required")
+
+	return errors.Join(errs...)
+}
+
+func validAddrOrPrefix(value string) bool {
+	if _, err := netip.ParsePrefix(value); err == nil {
+		return true
+	}
+	_, err := netip.ParseAddr(value)
+	return err == nil
+}
+
+func validURL(value string) bool {
+	u, err := url.Parse(value)
+	return err == nil && u.Scheme != "" && u.Host != ""
+}
@@ -0,0 +1,195 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const EnvPrefix = "CONDUIT_"
+
+// Load builds the config from args (without the program name) and the
+// environment. The settings are registered on fs, next to any flags the
+// caller defined. The file is taken from -config or CONDUIT_CONFIG. The
+// result is validated; arguments left after the flags are in fs.Args().
+func Load(fs *flag.FlagSet, args []string, lookupEnv func(string) (string, bool)) (*Config, error) {
+	cfg := Default()
+
+	path, _ := lookupEnv(EnvPrefix + "CONFIG")
+	if p, ok := configFlag(args); ok {
+		path = p
+	}
+
+	if path != "" {
+		if err := cfg.loadFile(path); err != nil {
+			return nil, err
+		}
+	}
+
+	fs.String("config", path, "path to a YAML config file (env "+EnvPrefix+"CONFIG)")
+	fs.BoolVar(&cfg.PrintConfig, "print-config", false, "print the effective config with secrets redacted and exit")
+
+	// only settings are read from the environment, not the caller's flags
+	others := map[string]bool{}
+	fs.VisitAll(func(f *flag.Flag) {
+		others[f.Name] = true
+	})
+
+	cfg.bind(fs)
+
+	var errs []error
+	fs.VisitAll(func(f *flag.Flag) {
+		if others[f.Name] {
+			return
+		}
+
+		env := EnvName(f.Name)
+		if value, ok := lookupEnv(env); ok {
+			if err := fs.Set(f.Name, value); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", env, err))
+			}
+		}
+	})
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// EnvName is the environment variable that sets the flag with this name.
+func EnvName(flagName string) string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(flagName, "-", "_"))
+}
+
+// configFlag finds -config before the flags are parsed, since the file
+// has to be loaded first for flags to override it.
+func configFlag(args []string) (string, bool) {
+	for i, arg := range args {
+		if arg == "--" {
+			break
+		}
+
+		name, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if !strings.HasPrefix(arg, "-") || name != "config" {
+			continue
+		}
+
+		if hasValue {
+			return value, true
+		}
+		if i+1 < len(args) {
+			return args[i+1], true
+		}
+	}
+
+	return "", false
+}
+
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading config: %w", err)
+	}
+
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("parsing config %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// Write prints the config as YAML with secrets redacted.
+func (c *Config) Write(w io.Writer) error {
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(c); err != nil {
+		return err
+	}
+
+	return enc.Close()
+}
+
+// bind registers a flag for every setting. Flags are named after the YAML
+// path, e.g. http.read-timeout is -http-read-timeout.
+func (c *Config) bind(fs *flag.FlagSet) {
+	fs.StringVar(&c.HTTP.Addr, "http-addr", c.HTTP.Addr, "listen address")
+	fs.DurationVar(&c.HTTP.ReadHeaderTimeout, "http-read-header-timeout", c.HTTP.ReadHeaderTimeout, "time allowed to read request headers")
+	fs.DurationVar(&c.HTTP.ReadTimeout, "http-read-timeout", c.HTTP.ReadTimeout, "time allowed to read a whole request")
+	fs.DurationVar(&c.HTTP.WriteTimeout, "http-write-timeout", c.HTTP.WriteTimeout, "time allowed to write a response")
+	fs.DurationVar(&c.HTTP.IdleTimeout, "http-idle-timeout", c.HTTP.IdleTimeout, "keep-alive idle timeout")
+	fs.DurationVar(&c.HTTP.ShutdownTimeout, "http-shutdown-timeout", c.HTTP.ShutdownTimeout, "graceful shutdown timeout")
+	fs.DurationVar(&c.HTTP.DrainDelay, "http-drain-delay", c.HTTP.DrainDelay, "how long /readyz fails before shutdown starts")
+	fs.StringVar(&c.HTTP.AdminAddr, "http-admin-addr", c.HTTP.AdminAddr, "admin listen address for /metrics, empty disables it")
+	fs.Var(&c.HTTP.TrustedProxies, "http-trusted-proxies", "CIDRs of proxies trusted to set X-Forwarded-For")
+
+	fs.Var(&c.Database.DSN, "database-dsn", "Postgres connection string")
+	fs.IntVar(&c.Database.MaxOpenConns, "database-max-open-conns", c.Database.MaxOpenConns, "connection pool size, 0 is unlimited")
+	fs.IntVar(&c.Database.MaxIdleConns, "database-max-idle-conns", c.Database.MaxIdleConns, "idle connections kept in the pool")
+	fs.DurationVar(&c.Database.ConnMaxLifetime, "database-conn-max-lifetime", c.Database.ConnMaxLifetime, "maximum connection age, 0 is unlimited")
+	fs.DurationVar(&c.Database.ConnMaxIdleTime, "database-conn-max-idle-time", c.Database.ConnMaxIdleTime, "maximum connection idle time, 0 is unlimited")
+	fs.DurationVar(&c.Database.ConnectTimeout, "database-connect-timeout", c.Database.ConnectTimeout, "timeout for the startup connection check")
+	fs.BoolVar(&c.Database.AutoMigrate, "database-auto-migrate", c.Database.AutoMigrate, "apply pending migrations on startup")
+	fs.BoolVar(&c.Database.RequireLatestSchema, "database-require-latest-schema", c.Database.RequireLatestSchema, "refuse to start while migrations are pending")
+
+	fs.StringVar(&c.JWT.Issuer, "jwt-issuer", c.JWT.Issuer, "iss claim of issued tokens")
+	fs.StringVar(&c.JWT.Audience, "jwt-audience", c.JWT.Audience, "aud claim of issued tokens")
+	fs.DurationVar(&c.JWT.TTL, "jwt-ttl", c.JWT.TTL, "access token lifetime")
+	fs.DurationVar(&c.JWT.Leeway, "jwt-leeway", c.JWT.Leeway, "allowed clock skew")
+	fs.Var(&c.JWT.Schemes, "jwt-schemes", "accepted Authorization schemes")
+	fs.StringVar(&c.JWT.SigningKey, "jwt-signing-key", c.JWT.SigningKey, "path of the Ed25519 private key")
+	fs.Var(&c.JWT.PublicKeys, "jwt-public-keys", "paths of extra verification keys")
+	fs.StringVar(&c.JWT.SessionStore, "jwt-session-store", c.JWT.SessionStore, "session store: memory or postgres")
+
+	fs.StringVar(&c.Password.Hash, "password-hash", c.Password.Hash, "algorithm for new password hashes: argon2id or bcrypt")
+	fs.UintVar(&c.Password.Argon2Memory, "password-argon2-memory", c.Password.Argon2Memory, "argon2id memory in KiB")
+	fs.UintVar(&c.Password.Argon2Time, "password-argon2-time", c.Password.Argon2Time, "argon2id passes")
+	fs.UintVar(&c.Password.Argon2Threads, "password-argon2-threads", c.Password.Argon2Threads, "argon2id parallelism")
+	fs.IntVar(&c.Password.BcryptCost, "password-bcrypt-cost", c.Password.BcryptCost, "bcrypt cost")
+
+	fs.StringVar(&c.Mail.From, "mail-from", c.Mail.From, "sender of outgoing mail")
+	fs.StringVar(&c.Mail.Outbox, "mail-outbox", c.Mail.Outbox, "write mail to this dir instead of sending it (logs when empty)")
+	fs.StringVar(&c.Mail.SMTPAddr, "mail-smtp-addr", c.Mail.SMTPAddr, "SMTP server host:port, enables SMTP delivery")
+	fs.StringVar(&c.Mail.SMTPUsername, "mail-smtp-username", c.Mail.SMTPUsername, "SMTP username")
+	fs.Var(&c.Mail.SMTPPassword, "mail-smtp-password", "SMTP password")
+
+	fs.StringVar(&c.App.URL, "app-url", c.App.URL, "frontend base URL used in emails")
+	fs.BoolVar(&c.App.RequireVerifiedEmail, "app-require-verified-email", c.App.RequireVerifiedEmail, "only verified users can create articles and comments")
+
+	fs.Var(&c.CORS.AllowedOrigins, "cors-allowed-origins", "origins allowed to call the API, * for any")
+	fs.Var(&c.CORS.AllowedMethods, "cors-allowed-methods", "methods allowed in cross-origin requests")
+	fs.Var(&c.CORS.AllowedHeaders, "cors-allowed-headers", "request headers allowed in cross-origin requests")
+	fs.Var(&c.CORS.ExposedHeaders, "cors-exposed-headers", "response headers exposed to cross-origin callers")
+	fs.BoolVar(&c.CORS.AllowCredentials, "cors-allow-credentials", c.CORS.AllowCredentials, "allow credentialed cross-origin requests")
+	fs.DurationVar(&c.CORS.MaxAge, "cors-max-age", c.CORS.MaxAge, "how long browsers may cache preflight responses")
+
+	fs.StringVar(&c.RateLimit.Store, "rate-limit-store", c.RateLimit.Store, "rate limit store: memory or postgres")
+	fs.Var(&c.RateLimit.Public, "rate-limit-public", "rate of anonymous reads, LIMIT/PERIOD or off")
+	fs.Var(&c.RateLimit.Credentials, "rate-limit-credentials", "rate of sign-up, login and password reset per IP")
+	fs.Var(&c.RateLimit.Auth, "rate-limit-auth", "rate of requests to routes taking credentials per IP, valid or not")
+	fs.Var(&c.RateLimit.User, "rate-limit-user", "rate of authenticated requests per user")
+	fs.Var(&c.RateLimit.Create, "rate-limit-create", "rate of new articles and comments per user")
+
+	fs.StringVar(&c.Tracing.Exporter, "tracing-exporter", c.Tracing.Exporter, "trace exporter: none, stdout, file or otlp")
+	fs.StringVar(&c.Tracing.File, "tracing-file", c.Tracing.File, "file the file exporter appends spans to")
+	fs.StringVar(&c.Tracing.Endpoint, "tracing-endpoint", c.Tracing.Endpoint, "OTLP/HTTP collector host:port")
+	fs.BoolVar(&c.Tracing.Insecure, "tracing-insecure", c.Tracing.Insecure, "send OTLP over plain HTTP")
+	fs.Float64Var(&c.Tracing.SampleRatio, "tracing-sample-ratio", c.Tracing.SampleRatio, "share of new traces to record, 0 to 1")
+	fs.StringVar(&c.Tracing.ServiceName, "tracing-service-name", c.Tracing.ServiceName, "service.name of exported spans")
+}
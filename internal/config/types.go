@@ -0,0 +1,109 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const redacted = "[redacted]"
+
+// Secret is a string that never shows up in logs or printed configs.
+type Secret string
+
+func (s Secret) String() string {
+	if s == "" {
+		return ""
+	}
+
+	return redacted
+}
+
+// Reveal returns the actual value.
+func (s Secret) Reveal() string {
+	return string(s)
+}
+
+func (s *Secret) Set(value string) error {
+	*s = Secret(value)
+	return nil
+}
+
+func (s Secret) MarshalYAML() (any, error) {
+	return s.String(), nil
+}
+
+// List is a YAML sequence, or a comma-separated string in environment
+// variables and flags.
+type List []string
+
+func (l List) String() string {
+	return strings.Join(l, ",")
+}
+
+func (l *List) Set(value string) error {
+	*l = nil
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			*l = append(*l, item)
+		}
+	}
+
+	return nil
+}
+
+// Rate is a request rate written LIMIT/PERIOD, e.g. 60/1m, or off.
+type Rate struct {
+	Limit  int
+	Period time.Duration
+}
+
+func (r Rate) String() string {
+	if r.Limit == 0 {
+		return "off"
+	}
+
+	return strconv.Itoa(r.Limit) + "/" + r.Period.String()
+}
+
+func (r *Rate) Set(value string) error {
+	value = strings.TrimSpace(value)
+	if value == "off" || value == "" {
+		*r = Rate{}
+		return nil
+	}
+
+	limit, period, ok := strings.Cut(value, "/")
+	if !ok {
+		return fmt.Errorf("rate %q is not LIMIT/PERIOD or off", value)
+	}
+
+	n, err := strconv.Atoi(limit)
+	if err != nil || n <= 0 {
+		return fmt.Errorf("rate %q: limit must be a positive number", value)
+	}
+
+	d, err := time.ParseDuration(period)
+	if err != nil || d <= 0 {
+		return fmt.Errorf("rate %q: period must be a positive duration", value)
+	}
+
+	*r = Rate{Limit: n, Period: d}
+	return nil
+}
+
+func (r Rate) MarshalYAML() (any, error) {
+	return r.String(), nil
+}
+
+func (r *Rate) UnmarshalYAML(node *yaml.Node) error {
+	var value string
+	if err := node.Decode(&value); err != nil {
+		return err
+	}
+
+	return r.Set(value)
+}
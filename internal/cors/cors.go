@@ -0,0 +1,93 @@
+// Package cors answers preflight requests and adds CORS headers for the
+// configured origins.
+package cors
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Options struct {
+	// AllowedOrigins lists origins like https://example.com, or * for any.
+	// CORS is off when it is empty.
+	AllowedOrigins   []string
+	AllowedMethods   []string
+	AllowedHeaders   []string
+	ExposedHeaders   []string
+	AllowCredentials bool
+	MaxAge           time.Duration
+}
+
+type cors struct {
+	opts    Options
+	any     bool
+	origins []string
+}
+
+func Handler(opts Options, next http.Handler) http.Handler {
+	if len(opts.AllowedOrigins) == 0 {
+		return next
+	}
+
+	c := &cors{opts: opts}
+	for _, origin := range opts.AllowedOrigins {
+		if origin == "*" {
+			c.any = true
+			continue
+		}
+		c.origins = append(c.origins, strings.ToLower(strings.TrimSuffix(origin, "/")))
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.serveHTTP(w, r, next)
+	})
+}
+
+func (c *cors) allowed(origin string) bool {
+	return c.any || slices.Contains(c.origins, strings.ToLower(origin))
+}
+
+func (c *cors) serveHTTP(w http.ResponseWriter, r *http.Request, next http.Handler) {
+	header := w.Header()
+	header.Add("Vary", "Origin")
+
+	origin := r.Header.Get("Origin")
+	if origin == "" || !c.allowed(origin) {
+		next.ServeHTTP(w, r)
+		return
+	}
+
+	if c.any && !c.opts.AllowCredentials {
+		header.Set("Access-Control-Allow-Origin", "*")
+	} else {
+		header.Set("Access-Control-Allow-Origin", origin)
+	}
+
+	if c.opts.AllowCredentials {
+		header.Set("Access-Control-Allow-Credentials", "true")
+	}
+
+	preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+	if !preflight {
+		if len(c.opts.ExposedHeaders) > 0 {
+			header.Set("Access-Control-Expose-Headers", strings.Join(c.opts.ExposedHeaders, ", "))
+		}
+		next.ServeHTTP(w, r)
+		return
+	}
+
+	header.Add("Vary", "Access-Control-Request-Method")
+	header.Add("Vary", "Access-Control-Request-Headers")
+	header.Set("Access-Control-Allow-Methods", strings.Join(c.opts.AllowedMethods, ", "))
+	if len(c.opts.AllowedHeaders) > 0 {
+		header.Set("Access-Control-Allow-Headers", strings.Join(c.opts.AllowedHeaders, ", "))
+	}
+	if c.opts.MaxAge > 0 {
+		header.Set("Access-Control-Max-Age", strconv.Itoa(int(c.opts.MaxAge.Seconds())))
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,33 @@
+package entity
+
+import "fmt"
+
+type Role string
+
+const (
+	RoleUser      Role = "user"
+	RoleModerator Role = "moderator"
+	RoleAdmin     Role = "admin"
+)
+
+var roleRanks = map[Role]int{
+	RoleUser:      0,
+	RoleModerator: 1,
+	RoleAdmin:     2,
+}
+
+func ParseRole(value string) (Role, error) {
+	role := Role(value)
+	if _, ok := roleRanks[role]; !ok {
+		return "", fmt.Errorf("unknown role %q", value)
+	}
+
+	return role, nil
+}
+
+// AtLeast reports whether r grants everything other does. Roles are ordered
+// user < moderator < admin.
+func (r Role) AtLeast(other Role) bool {
+	rank, ok := roleRanks[r]
+	return ok && rank >= roleRanks[other]
+}
@@ -0,0 +1,17 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/guregu/null/v5"
+)
+
+type PersonalAccessToken struct {
+	ID         uint64    `json:"id"`
+	Name       string    `json:"name"`
+	Scopes     []string  `json:"scopes"`
+	ExpiresAt  null.Time `json:"expiresAt"`
+	LastUsedAt null.Time `json:"lastUsedAt"`
+	CreatedAt  time.Time `json:"createdAt"`
+	Token      *string   `json:"token,omitempty"`
+}
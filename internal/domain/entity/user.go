@@ -1,13 +1,31 @@
 package entity
 
-import "github.com/guregu/null/v5"
+import (
+	"time"
+
+	"github.com/guregu/null/v5"
+)
 
 type User struct {
-	ID       uint64      `json:"id"       db:"id"`
-	Email    string      `json:"email"    db:"email"`
-	Username string      `json:"username" db:"username"`
-	Bio      string      `json:"bio"      db:"bio"`
-	Image    null.String `json:"image"    db:"image"`
-	Password string      `json:"-"        db:"password"`
-	Token    *string     `json:"token"`
+	ID              uint64      `json:"id"              db:"id"`
+	Email           string      `json:"email"           db:"email"`
+	Username        string      `json:"username"        db:"username"`
+	Bio             string      `json:"bio"             db:"bio"`
+	Image           null.String `json:"image"           db:"image"`
+	Password        string      `json:"-"               db:"password"`
+	EmailVerifiedAt null.Time   `json:"emailVerifiedAt" db:"email_verified_at"`
+	Role            Role        `json:"role"            db:"role"`
+	CreatedAt       time.Time   `json:"createdAt"       db:"created_at"`
+	LastLoginAt     null.Time   `json:"lastLoginAt"     db:"last_login_at"`
+	SuspendedAt     null.Time   `json:"suspendedAt"     db:"suspended_at"`
+	Token           *string     `json:"token"`
+	RefreshToken    *string     `json:"refreshToken,omitempty"`
+}
+
+func (u *User) EmailVerified() bool {
+	return u.EmailVerifiedAt.Valid
+}
+
+func (u *User) Suspended() bool {
+	return u.SuspendedAt.Valid
 }
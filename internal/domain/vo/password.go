@@ -2,8 +2,6 @@ package vo
 
 import (
 	"errors"
-
-	"golang.org/x/crypto/bcrypt"
 )
 
 type Password string
@@ -17,15 +15,22 @@ func NewPassword(value string) (Password, error) {
 }
 
 func (p Password) Hash() (string, error) {
-	hash, err := bcrypt.GenerateFromPassword([]byte(p), 13)
+	return currentPasswordHashing().Default.Hash([]byte(p))
+}
+
+func (p Password) Compare(hash string) bool {
+	h, err := currentPasswordHashing().hasherFor(hash)
 	if err != nil {
-		return "", err
+		return false
 	}
 
-	return string(hash), nil
+	ok, err := h.Verify([]byte(p), hash)
+	return err == nil && ok
 }
 
-func (p Password) Compare(hash string) bool {
-	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(p))
-	return err == nil
+// NeedsRehash reports whether hash should be replaced by a fresh one made
+// with the current default algorithm and parameters.
+func (p Password) NeedsRehash(hash string) bool {
+	h := currentPasswordHashing().Default
+	return !h.Owns(hash) || h.Outdated(hash)
 }
@@ -0,0 +1,201 @@
+package vo
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var ErrUnknownPasswordHash = errors.New("unknown password hash format")
+
+// PasswordHasher produces self-describing hashes: the encoded string starts
+// with the algorithm and carries its parameters, so hashes made with older
+// settings keep verifying after the settings change.
+type PasswordHasher interface {
+	Hash(password []byte) (string, error)
+	Verify(password []byte, encoded string) (bool, error)
+	// Owns reports whether encoded was produced by this algorithm.
+	Owns(encoded string) bool
+	// Outdated reports whether encoded was made with other parameters.
+	Outdated(encoded string) bool
+}
+
+type BcryptHasher struct {
+	Cost int
+}
+
+func (h BcryptHasher) Hash(password []byte) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword(password, h.Cost)
+	if err != nil {
+		return "", err
+	}
+
+	return string(hash), nil
+}
+
+func (h BcryptHasher) Verify(password []byte, encoded string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(encoded), password)
+	if err != nil {
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (h BcryptHasher) Owns(encoded string) bool {
+	return strings.HasPrefix(encoded, "$2a$") ||
+		strings.HasPrefix(encoded, "$2b$") ||
+		strings.HasPrefix(encoded, "$2y$")
+}
+
+func (h BcryptHasher) Outdated(encoded string) bool {
+	cost, err := bcrypt.Cost([]byte(encoded))
+	return err != nil || cost != h.Cost
+}
+
+// Argon2idHasher encodes hashes in the PHC string format:
+// $argon2id$v=19$m=<KiB>,t=<passes>,p=<threads>$<salt>$<key>
+type Argon2idHasher struct {
+	Memory  uint32
+	Time    uint32
+	Threads uint8
+	SaltLen uint32
+	KeyLen  uint32
+}
+
+const argon2idPrefix = "$argon2id$"
+
+var argon2Encoding = base64.RawStdEncoding
+
+func DefaultArgon2idHasher() Argon2idHasher {
+	return Argon2idHasher{
+		Memory:  64 * 1024,
+		Time:    3,
+		Threads: 2,
+		SaltLen: 16,
+		KeyLen:  32,
+	}
+}
+
+func (h Argon2idHasher) Hash(password []byte) (string, error) {
+	salt := make([]byte, h.SaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	key := argon2.IDKey(password, salt, h.Time, h.Memory, h.Threads, h.KeyLen)
+
+	return fmt.Sprintf(
+		"%sv=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2idPrefix, argon2.Version, h.Memory, h.Time, h.Threads,
+		argon2Encoding.EncodeToString(salt), argon2Encoding.EncodeToString(key),
+	), nil
+}
+
+func (h Argon2idHasher) decode(encoded string) (Argon2idHasher, []byte, []byte, error) {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return h, nil, nil, ErrUnknownPasswordHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return h, nil, nil, ErrUnknownPasswordHash
+	}
+
+	var params Argon2idHasher
+	_, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Time, &params.Threads)
+	if err != nil {
+		return h, nil, nil, ErrUnknownPasswordHash
+	}
+
+	salt, err := argon2Encoding.DecodeString(parts[4])
+	if err != nil {
+		return h, nil, nil, ErrUnknownPasswordHash
+	}
+
+	key, err := argon2Encoding.DecodeString(parts[5])
+	if err != nil {
+		return h, nil, nil, ErrUnknownPasswordHash
+	}
+
+	params.SaltLen = uint32(len(salt))
+	params.KeyLen = uint32(len(key))
+
+	return params, salt, key, nil
+}
+
+func (h Argon2idHasher) Verify(password []byte, encoded string) (bool, error) {
+	params, salt, key, err := h.decode(encoded)
+	if err != nil {
+		return false, err
+	}
+
+	actual := argon2.IDKey(password, salt, params.Time, params.Memory, params.Threads, params.KeyLen)
+
+	return subtle.ConstantTimeCompare(actual, key) == 1, nil
+}
+
+func (h Argon2idHasher) Owns(encoded string) bool {
+	return strings.HasPrefix(encoded, argon2idPrefix)
+}
+
+func (h Argon2idHasher) Outdated(encoded string) bool {
+	params, _, _, err := h.decode(encoded)
+	return err != nil || params != h
+}
+
+// PasswordHashing hashes new passwords with Default and verifies existing
+// hashes with whichever known hasher owns them.
+type PasswordHashing struct {
+	Default PasswordHasher
+	Legacy  []PasswordHasher
+}
+
+func (p *PasswordHashing) hasherFor(encoded string) (PasswordHasher, error) {
+	if p.Default.Owns(encoded) {
+		return p.Default, nil
+	}
+
+	for _, h := range p.Legacy {
+		if h.Owns(encoded) {
+			return h, nil
+		}
+	}
+
+	return nil, ErrUnknownPasswordHash
+}
+
+var (
+	passwordHashingMu sync.RWMutex
+	passwordHashing   = &PasswordHashing{
+		Default: DefaultArgon2idHasher(),
+		Legacy:  []PasswordHasher{BcryptHasher{Cost: 13}},
+	}
+)
+
+// SetPasswordHashing replaces the hashing settings used by Password. It is
+// meant to be called once at startup.
+func SetPasswordHashing(p *PasswordHashing) {
+	passwordHashingMu.Lock()
+	defer passwordHashingMu.Unlock()
+
+	passwordHashing = p
+}
+
+func currentPasswordHashing() *PasswordHashing {
+	passwordHashingMu.RLock()
+	defer passwordHashingMu.RUnlock()
+
+	return passwordHashing
+}
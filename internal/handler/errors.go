@@ -92,6 +92,11 @@ func UnauthorizedError(w http.ResponseWriter) {
 		Write(w)
 }
 
+func ForbiddenError(w http.ResponseWriter) {
+	NewError("Forbidden", http.StatusForbidden).
+		Write(w)
+}
+
 func AlreayExistsError(w http.ResponseWriter) {
 	NewError("resource already exists", http.StatusBadRequest).
 		Write(w)
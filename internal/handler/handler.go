@@ -2,168 +2,284 @@ package handler
 
 import (
 	"context"
-	"encoding/json"
-	"errors"
-	"fmt"
+	"log/slog"
 	"net/http"
-	"strings"
+	"runtime/debug"
+	"sync/atomic"
+	"time"
 
 	"github.com/askerdev/realworld-clone-go/internal/domain/entity"
+	"github.com/askerdev/realworld-clone-go/internal/mail"
+	"github.com/askerdev/realworld-clone-go/internal/mem"
+	"github.com/askerdev/realworld-clone-go/internal/metrics"
+	"github.com/askerdev/realworld-clone-go/internal/migrate"
 	"github.com/askerdev/realworld-clone-go/internal/postgres"
+	"github.com/askerdev/realworld-clone-go/internal/tracing"
+	"github.com/askerdev/realworld-clone-go/pkg/ratelimit"
+	"github.com/askerdev/realworld-clone-go/pkg/realip"
+	"github.com/askerdev/realworld-clone-go/pkg/requestlog"
+	"github.com/askerdev/realworld-clone-go/pkg/router"
 	"github.com/askerdev/realworld-clone-go/pkg/simplejwt"
-	"github.com/golang-jwt/jwt/v5"
-	"github.com/guregu/null/v5"
 	"github.com/jmoiron/sqlx"
 )
 
+// userCacheTTL is how long a replica serves a user from its cache, and so
+// how long a change made through another replica, such as a role change,
+// can take to show there.
+const userCacheTTL = 30 * time.Second
+
+type Options struct {
+	// AppURL is the frontend base URL used to build links sent by email.
+	AppURL string
+	// RequireVerifiedEmail blocks unverified users from creating articles
+	// and comments.
+	RequireVerifiedEmail bool
+	// Metrics records request and business metrics; nil records nothing.
+	Metrics *metrics.Metrics
+	// Now is the clock 2FA codes, challenges and lockouts are checked
+	// against; nil uses time.Now.
+	Now func() time.Time
+	// Migrator lets /readyz check the schema is up to date; nil skips the
+	// check.
+	Migrator *migrate.Migrator
+	// RealIP finds the client address behind trusted proxies; nil uses the
+	// peer address.
+	RealIP *realip.Resolver
+	// RateLimitStore keeps the rate limit buckets; nil disables limiting.
+	RateLimitStore ratelimit.Store
+	RateLimits     RateLimits
+	// Logger writes the access log and is what handlers log through; nil
+	// uses slog.Default.
+	Logger *slog.Logger
+}
+
 type handler struct {
 	storage       *postgres.Storage
+	users         *mem.UserCache
 	issuer        *simplejwt.Issuer
 	validator     *simplejwt.Validator
 	jwtMiddleware *simplejwt.Middleware
+	mailer        mail.Mailer
+	opts          Options
+	metrics       *metrics.Metrics
+	now           func() time.Time
+	router        *router.Router
+	realIP        *realip.Resolver
+	logger        *slog.Logger
+	limiter       *ratelimit.Limiter
+	draining      atomic.Bool
 }
 
-func New(db *sqlx.DB, issuer *simplejwt.Issuer, validator *simplejwt.Validator) *handler {
-	return &handler{
-		storage:       postgres.NewStorage(db),
+func New(
+	db *sqlx.DB,
+	issuer *simplejwt.Issuer,
+	validator *simplejwt.Validator,
+	mailer mail.Mailer,
+	opts Options,
+) *handler {
+	storage := postgres.NewStorage(db)
+	if opts.Metrics != nil {
+		storage.Observe(opts.Metrics)
+	}
+	users := mem.NewUserCache(storage.SelectActiveUserByID, userCacheTTL)
+
+	h := &handler{
+		storage:       storage,
+		users:         users,
 		issuer:        issuer,
 		validator:     validator,
-		jwtMiddleware: simplejwt.NewMiddleware(validator),
+		jwtMiddleware: simplejwt.NewMiddleware(validator, users, storage),
+		mailer:        mailer,
+		opts:          opts,
+		metrics:       opts.Metrics,
+		now:           opts.Now,
+		realIP:        opts.RealIP,
+		logger:        opts.Logger,
+	}
+	if h.now == nil {
+		h.now = time.Now
 	}
+	if h.logger == nil {
+		h.logger = slog.Default()
+	}
+	if h.realIP == nil {
+		h.realIP, _ = realip.New()
+	}
+	if opts.RateLimitStore != nil {
+		h.limiter = ratelimit.New(opts.RateLimitStore, tooManyRequests, rateLimitError)
+	}
+	h.router = h.routes()
+
+	return h
+}
+
+// RunUserCacheEviction drops expired users from the cache authenticated
+// requests load users through.
+func (h *handler) RunUserCacheEviction(ctx context.Context, interval time.Duration) {
+	h.users.RunEviction(ctx, interval)
 }
 
 func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	m := http.NewServeMux()
-
-	// chiR := chi.NewRouter()
-
-	// chiR.Get("/health", h.healthCheck)
-	m.HandleFunc("GET /health", h.healthCheck)
-	// chiR.Post("/api/users", h.register)
-	m.HandleFunc("POST /api/users", h.register)
-	// chiR.Post("/api/users/login", h.login)
-	m.HandleFunc("POST /api/users/login", h.login)
-	// chiR.Get("/api/profiles/{username}", h.profile)
-	m.HandleFunc("GET /api/profiles/{username}", h.profile)
-	// chiR.Get("/api/articles", h.listArticle)
-	m.HandleFunc("GET /api/articles", h.listArticle)
-	// chiR.Get("/api/articles/{slug}", h.articleBySlug)
-	m.HandleFunc("GET /api/articles/{slug}", h.articleBySlug)
-	// r.Get("/api/articles/feed", h.feedArticles)
-	m.HandleFunc(
-		"GET /api/articles/feed",
-		// this is done like that because "most specific" rule not working with subrouting
-		h.jwtMiddleware.HandleHTTP(http.HandlerFunc(h.feedArticles)).ServeHTTP,
-	)
-	// chiR.Get("/api/tags", h.listTags)
-	m.HandleFunc("GET /api/tags", h.listTags)
-	// chiR.Get("/api/articles/{slug}/comments", h.listComments)
-	m.HandleFunc("GET /api/articles/{slug}/comments", h.listComments)
-
-	auth := http.NewServeMux()
-	// r.Get("/api/user", h.user)
-	auth.HandleFunc("GET /api/user", h.user)
-	// r.Put("/api/user", h.updateUser)
-	auth.HandleFunc("PUT /api/user", h.updateUser)
-	// r.Post("/api/profiles/{username}/follow", h.follow)
-	auth.HandleFunc("POST /api/profiles/{username}/follow", h.follow)
-	// r.Delete("/api/profiles/{username}/follow", h.unfollow)
-	auth.HandleFunc("DELETE /api/profiles/{username}/follow", h.unfollow)
-	// r.Post("/api/articles", h.createArticle)
-	auth.HandleFunc("POST /api/articles", h.createArticle)
-	// r.Put("/api/articles/{slug}", h.updateArticle)
-	auth.HandleFunc("PUT /api/articles/{slug}", h.updateArticle)
-	// r.Delete("/api/articles/{slug}", h.deleteArticle)
-	auth.HandleFunc("DELETE /api/articles/{slug}", h.deleteArticle)
-	// r.Post("/api/articles/{slug}/favorite", h.favoriteArticle)
-	auth.HandleFunc("POST /api/articles/{slug}/favorite", h.favoriteArticle)
-	// r.Delete("/api/articles/{slug}/favorite", h.unfavoriteArticle)
-	auth.HandleFunc("DELETE /api/articles/{slug}/favorite", h.unfavoriteArticle)
-	// r.Post("/api/articles/{slug}/comments", h.createComment)
-	auth.HandleFunc("POST /api/articles/{slug}/comments", h.createComment)
-	// r.Delete("/api/articles/{slug}/comments/{id}", h.deleteComment)
-	auth.HandleFunc("DELETE /api/articles/{slug}/comments/{id}", h.deleteComment)
-
-	m.Handle("/", h.jwtMiddleware.HandleHTTP(auth))
-
-	m.ServeHTTP(w, r)
+	h.router.ServeHTTP(w, r)
 }
 
-func (h *handler) healthCheck(w http.ResponseWriter, _ *http.Request) {
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]any{
-		"message": "ok!",
-	})
+// routes registers every route once, when the handler is built.
+func (h *handler) routes() *router.Router {
+	r := router.New()
+	r.Use(requestlog.Middleware(h.logger), tracing.Middleware, h.metrics.Middleware, h.recoverPanic)
+
+	limits := h.opts.RateLimits
+	create := h.rateLimit("create", limits.Create, h.userKey)
+
+	probes := r.Group()
+	probes.HandleFunc("GET /livez", h.livez)
+	probes.HandleFunc("GET /readyz", h.readyz)
+
+	public := r.Group(h.rateLimit("public", limits.Public, h.ipKey))
+	public.HandleFunc("GET /.well-known/jwks.json", h.jwks)
+	public.HandleFunc("GET /api/tags", h.listTags)
+
+	credentials := r.Group(h.rateLimit("credentials", limits.Credentials, h.ipKey))
+	credentials.HandleFunc("POST /api/users", h.register)
+	credentials.HandleFunc("POST /api/users/login", h.login)
+	credentials.HandleFunc("POST /api/users/login/2fa", h.loginTwoFactor)
+	credentials.HandleFunc("POST /api/users/token/refresh", h.refreshToken)
+	credentials.HandleFunc("POST /api/users/password/forgot", h.forgotPassword)
+	credentials.HandleFunc("POST /api/users/password/reset", h.resetPassword)
+	credentials.HandleFunc("POST /api/users/verify", h.verifyEmail)
+
+	// The per-IP auth limit runs before the credentials are checked, so
+	// bogus tokens, which never reach the per-user limits, are limited too.
+	authIP := h.rateLimit("auth", limits.Auth, h.ipKey)
+
+	optional := r.Group(authIP, h.jwtMiddleware.HandleHTTPOptional, h.identify, h.rateLimit("public", limits.Public, h.userKey))
+	optional.HandleFunc("GET /api/profiles/{username}", h.profile)
+	optional.HandleFunc("GET /api/articles", h.listArticle)
+	optional.HandleFunc("GET /api/articles/{slug}", h.articleBySlug)
+	optional.HandleFunc("GET /api/articles/{slug}/comments", h.listComments)
+
+	auth := r.Group(authIP, h.jwtMiddleware.HandleHTTP, h.identify, h.rateLimit("user", limits.User, h.userKey))
+	auth.HandleFunc("GET /api/user", h.user)
+	auth.HandleFunc("PUT /api/user", h.updateUser, h.scope(scopeUserWrite))
+	auth.HandleFunc("GET /api/articles/feed", h.feedArticles)
+	auth.HandleFunc("POST /api/profiles/{username}/follow", h.follow, h.scope(scopeProfilesWrite))
+	auth.HandleFunc("DELETE /api/profiles/{username}/follow", h.unfollow, h.scope(scopeProfilesWrite))
+	auth.HandleFunc("POST /api/articles", h.createArticle, h.scope(scopeArticlesWrite), h.requireVerified, create)
+	auth.HandleFunc("PUT /api/articles/{slug}", h.updateArticle, h.scope(scopeArticlesWrite))
+	auth.HandleFunc("DELETE /api/articles/{slug}", h.deleteArticle, h.scope(scopeArticlesWrite))
+	auth.HandleFunc("POST /api/articles/{slug}/favorite", h.favoriteArticle, h.scope(scopeFavoritesWrite))
+	auth.HandleFunc("DELETE /api/articles/{slug}/favorite", h.unfavoriteArticle, h.scope(scopeFavoritesWrite))
+	auth.HandleFunc("POST /api/articles/{slug}/comments", h.createComment, h.scope(scopeCommentsWrite), h.requireVerified, create)
+	auth.HandleFunc("DELETE /api/articles/{slug}/comments/{id}", h.deleteComment, h.scope(scopeCommentsWrite))
+
+	session := auth.Group(h.scope(scopeSession))
+	session.HandleFunc("POST /api/users/logout", h.logout)
+	session.HandleFunc("POST /api/users/logout-all", h.logoutAll)
+	session.HandleFunc("POST /api/users/verify/resend", h.resendVerification)
+	session.HandleFunc("POST /api/user/2fa", h.enrollTwoFactor)
+	session.HandleFunc("POST /api/user/2fa/confirm", h.confirmTwoFactor)
+	session.HandleFunc("DELETE /api/user/2fa", h.disableTwoFactor)
+	session.HandleFunc("GET /api/user/tokens", h.listTokens)
+	session.HandleFunc("POST /api/user/tokens", h.createToken)
+	session.HandleFunc("DELETE /api/user/tokens/{id}", h.deleteToken)
+
+	admin := session.Group(h.requireAdmin)
+	admin.HandleFunc("GET /api/admin/users", h.adminListUsers)
+	admin.HandleFunc("GET /api/admin/users/{id}", h.adminUser)
+	admin.HandleFunc("POST /api/admin/users/{id}/suspend", h.adminSuspendUser)
+	admin.HandleFunc("DELETE /api/admin/users/{id}/suspend", h.adminUnsuspendUser)
+	admin.HandleFunc("POST /api/admin/users/{id}/password-reset", h.adminResetPassword)
+	admin.HandleFunc("POST /api/admin/users/{id}/unlock", h.adminUnlockUser)
+	admin.HandleFunc("DELETE /api/admin/users/{id}", h.adminDeleteUser)
+
+	return r
 }
 
-func (h *handler) getHeaderToken(header http.Header) (*jwt.Token, error) {
-	auth := header.Get("Authorization")
-	if len(auth) < 8 || !strings.HasPrefix(auth, "Token ") {
-		return nil, fmt.Errorf("invalid header")
+// scope only lets personal access tokens through that carry scope.
+func (h *handler) scope(scope string) router.Middleware {
+	return func(next http.Handler) http.Handler {
+		return h.jwtMiddleware.RequireScope(scope, next)
 	}
+}
 
-	tokenString := strings.TrimPrefix(auth, "Token ")
+// recoverPanic turns a panic into a logged stack trace and a 500 with the
+// usual error body, instead of a dropped connection. It runs last among
+// the global middleware so the others still see the 500.
+func (h *handler) recoverPanic(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sw := router.NewStatusWriter(w)
 
-	token, err := h.validator.Validate(tokenString)
-	if err != nil {
-		return nil, fmt.Errorf("invalid token: %w", err)
-	}
+		defer func() {
+			v := recover()
+			if v == nil {
+				return
+			}
+			if v == http.ErrAbortHandler {
+				panic(v)
+			}
+
+			logger(r.Context()).Error(
+				"panic serving request",
+				slog.Any("panic", v),
+				slog.String("stack", string(debug.Stack())),
+			)
 
-	return token, nil
+			if !sw.Written() {
+				InternalServerError(sw)
+			}
+		}()
+
+		next.ServeHTTP(sw, r)
+	})
 }
 
-func (h *handler) userFromToken(token *jwt.Token) (*entity.User, error) {
+// identify adds the authenticated user, if any, to the request's log lines.
+func (h *handler) identify(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if u, err := simplejwt.ContextUser(r.Context()); err == nil {
+			requestlog.SetUser(r.Context(), u.ID)
+		}
 
-	claims := token.Claims.(jwt.MapClaims)
-	sub, ok := claims["sub"].(map[string]any)
-	if !ok {
-		return nil, errors.New("invalid token claims")
-	}
-	id, ok := sub["id"].(float64)
-	if !ok {
-		return nil, errors.New("invalid token claims")
-	}
-	email, ok := sub["email"].(string)
-	if !ok {
-		return nil, errors.New("invalid token claims")
-	}
-	username, ok := sub["username"].(string)
-	if !ok {
-		return nil, errors.New("invalid token claims")
-	}
-	bio, ok := sub["bio"].(string)
-	if !ok {
-		return nil, errors.New("invalid token claims")
-	}
+		next.ServeHTTP(w, r)
+	})
+}
 
-	var image null.String
-	if img, ok := sub["image"].(string); ok {
-		image.Valid = true
-		image.String = img
-	}
+// logger returns the request-scoped logger carrying the request ID, route
+// and user.
+func logger(ctx context.Context) *slog.Logger {
+	return requestlog.FromContext(ctx)
+}
 
-	return &entity.User{
-		ID:       uint64(id),
-		Email:    email,
-		Username: username,
-		Bio:      bio,
-		Image:    image,
-	}, nil
+func (h *handler) jwks(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	JSON(w, h.validator.JWKS())
 }
 
-func (h *handler) ContextUser(ctx context.Context) (*entity.User, error) {
-	token, err := simplejwt.ContextToken(ctx)
+// viewerID returns the ID of the authenticated user on routes with optional
+// auth, or nil for anonymous requests.
+func (h *handler) viewerID(ctx context.Context) *uint64 {
+	u, err := simplejwt.ContextUser(ctx)
 	if err != nil {
-		return nil, err
+		return nil
 	}
-	return h.userFromToken(token)
+
+	return &u.ID
+}
+
+func (h *handler) ContextUser(ctx context.Context) (*entity.User, error) {
+	return simplejwt.ContextUser(ctx)
 }
 
-func (h *handler) MustContextUser(ctx context.Context) *entity.User {
-	u, err := h.ContextUser(ctx)
+// contextUser returns the authenticated user. Routes behind the auth
+// middleware always have one; should one be missing anyway, the request is
+// answered with 401 and ok is false.
+func (h *handler) contextUser(w http.ResponseWriter, r *http.Request) (*entity.User, bool) {
+	u, err := h.ContextUser(r.Context())
 	if err != nil {
-		panic(err)
+		logger(r.Context()).Error(err.Error())
+		UnauthorizedError(w)
+		return nil, false
 	}
 
-	return u
+	return u, true
 }
@@ -0,0 +1,281 @@
+package handler
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/askerdev/realworld-clone-go/internal/domain/entity"
+	"github.com/askerdev/realworld-clone-go/internal/policy"
+	"github.com/askerdev/realworld-clone-go/internal/postgres"
+	"github.com/guregu/null/v5"
+)
+
+// disabledPasswordHash is not owned by any password hasher, so no password
+// compares equal to it until the user goes through a reset.
+const disabledPasswordHash = "!"
+
+func accountSuspended(w http.ResponseWriter) {
+	NewError("account is suspended", http.StatusForbidden).
+		Write(w)
+}
+
+// requireAdmin limits a route to users allowed to manage users.
+func (h *handler) requireAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		u, ok := h.contextUser(w, r)
+		if !ok {
+			return
+		}
+
+		if !policy.CanManageUsers(u) {
+			ForbiddenError(w)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// managedUser loads the user addressed by the {id} path value and checks
+// that the current admin may act on them. It writes the error response and
+// returns nil when it can't.
+func (h *handler) managedUser(w http.ResponseWriter, r *http.Request) *entity.User {
+	id, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
+	if err != nil {
+		NotFoundError(w)
+		return nil
+	}
+
+	target, err := h.storage.SelectUserByID(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			NotFoundError(w)
+			break
+		default:
+			logger(r.Context()).Error(err.Error())
+			InternalServerError(w)
+			break
+		}
+		return nil
+	}
+
+	u, ok := h.contextUser(w, r)
+	if !ok {
+		return nil
+	}
+
+	if !policy.CanManageUser(u, target) {
+		ForbiddenError(w)
+		return nil
+	}
+
+	return target
+}
+
+func (h *handler) adminListUsers(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	params := &postgres.SelectUsersParams{}
+	if query := q.Get("q"); query != "" {
+		params.Query = null.StringFrom(query)
+	}
+
+	if limit, err := strconv.Atoi(q.Get("limit")); err == nil {
+		params.Limit = null.IntFrom(int64(limit))
+	}
+
+	if offset, err := strconv.Atoi(q.Get("offset")); err == nil {
+		params.Offset = null.IntFrom(int64(offset))
+	}
+
+	users, count, err := h.storage.SelectUsers(r.Context(), params)
+	if err != nil {
+		logger(r.Context()).Error(err.Error())
+		InternalServerError(w)
+		return
+	}
+
+	JSON(w, map[string]any{
+		"users":      users,
+		"usersCount": count,
+	})
+}
+
+func (h *handler) adminUser(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
+	if err != nil {
+		NotFoundError(w)
+		return
+	}
+
+	u, err := h.storage.SelectUserByID(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			NotFoundError(w)
+			break
+		default:
+			logger(r.Context()).Error(err.Error())
+			InternalServerError(w)
+			break
+		}
+		return
+	}
+
+	twoFactor, err := h.twoFactorEnabled(r.Context(), u.ID)
+	if err != nil {
+		logger(r.Context()).Error(err.Error())
+		InternalServerError(w)
+		return
+	}
+
+	lockedUntil, err := h.storage.LoginLockedUntil(r.Context(), h.now(), accountLoginThrottle.key(u.Email))
+	if err != nil {
+		logger(r.Context()).Error(err.Error())
+		InternalServerError(w)
+		return
+	}
+
+	JSON(w, map[string]any{
+		"user":             u,
+		"twoFactorEnabled": twoFactor,
+		"lockedUntil":      lockedUntil,
+	})
+}
+
+func (h *handler) adminSuspendUser(w http.ResponseWriter, r *http.Request) {
+	h.setUserSuspended(w, r, true)
+}
+
+func (h *handler) adminUnsuspendUser(w http.ResponseWriter, r *http.Request) {
+	h.setUserSuspended(w, r, false)
+}
+
+func (h *handler) setUserSuspended(w http.ResponseWriter, r *http.Request, suspended bool) {
+	target := h.managedUser(w, r)
+	if target == nil {
+		return
+	}
+
+	u, err := h.storage.SetUserSuspended(r.Context(), target.ID, suspended)
+	if err != nil {
+		switch {
+		case errors.Is(err, postgres.ErrNotFound):
+			NotFoundError(w)
+			break
+		default:
+			logger(r.Context()).Error(err.Error())
+			InternalServerError(w)
+			break
+		}
+		return
+	}
+
+	// moving the watermark kills the user's access tokens right away
+	if suspended {
+		if err := h.issuer.RevokeAll(u.ID); err != nil {
+			logger(r.Context()).Error(err.Error())
+			InternalServerError(w)
+			return
+		}
+	}
+
+	h.users.Invalidate(u.ID)
+
+	JSON(w, map[string]any{
+		"user": u,
+	})
+}
+
+// adminResetPassword disables the current password, ends every session and
+// mails the user a reset link.
+func (h *handler) adminResetPassword(w http.ResponseWriter, r *http.Request) {
+	target := h.managedUser(w, r)
+	if target == nil {
+		return
+	}
+
+	_, err := h.storage.UpdateUser(r.Context(), &postgres.UpdateUserParams{
+		ID:       target.ID,
+		Password: null.StringFrom(disabledPasswordHash),
+	})
+	if err != nil {
+		logger(r.Context()).Error(err.Error())
+		InternalServerError(w)
+		return
+	}
+
+	if err := h.issuer.RevokeAll(target.ID); err != nil {
+		logger(r.Context()).Error(err.Error())
+		InternalServerError(w)
+		return
+	}
+
+	h.users.Invalidate(target.ID)
+
+	if err := h.sendPasswordResetEmail(r.Context(), target); err != nil {
+		logger(r.Context()).Error(err.Error())
+		InternalServerError(w)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *handler) adminUnlockUser(w http.ResponseWriter, r *http.Request) {
+	target := h.managedUser(w, r)
+	if target == nil {
+		return
+	}
+
+	err := h.storage.UnlockLogin(r.Context(), accountLoginThrottle.key(target.Email), h.now())
+	if err != nil {
+		switch {
+		case errors.Is(err, postgres.ErrNotFound):
+			NotFoundError(w)
+			break
+		default:
+			logger(r.Context()).Error(err.Error())
+			InternalServerError(w)
+			break
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *handler) adminDeleteUser(w http.ResponseWriter, r *http.Request) {
+	target := h.managedUser(w, r)
+	if target == nil {
+		return
+	}
+
+	// revoke first: the postgres session store references the user row
+	if err := h.issuer.RevokeAll(target.ID); err != nil {
+		logger(r.Context()).Error(err.Error())
+		InternalServerError(w)
+		return
+	}
+
+	err := h.storage.DeleteUser(r.Context(), target.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, postgres.ErrNotFound):
+			NotFoundError(w)
+			break
+		default:
+			logger(r.Context()).Error(err.Error())
+			InternalServerError(w)
+			break
+		}
+		return
+	}
+
+	h.users.Invalidate(target.ID)
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -3,10 +3,11 @@ package handler
 import (
 	"database/sql"
 	"errors"
-	"log/slog"
 	"net/http"
 	"strconv"
 
+	"github.com/askerdev/realworld-clone-go/internal/domain/entity"
+	"github.com/askerdev/realworld-clone-go/internal/policy"
 	"github.com/askerdev/realworld-clone-go/internal/postgres"
 	"github.com/gosimple/slug"
 	"github.com/guregu/null/v5"
@@ -30,7 +31,10 @@ func (h *handler) createArticle(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	u := h.MustContextUser(r.Context())
+	u, ok := h.contextUser(w, r)
+	if !ok {
+		return
+	}
 	slug := slug.Make(body.Article.Title)
 	article, err := h.storage.CreateArticle(
 		r.Context(),
@@ -44,11 +48,13 @@ func (h *handler) createArticle(w http.ResponseWriter, r *http.Request) {
 		},
 	)
 	if err != nil {
-		slog.Error(err.Error())
+		logger(r.Context()).Error(err.Error())
 		AlreayExistsError(w)
 		return
 	}
 
+	h.metrics.ArticleCreated()
+
 	JSON(w, map[string]any{
 		"article": article,
 	})
@@ -74,7 +80,10 @@ func (h *handler) feedArticles(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	u := h.MustContextUser(r.Context())
+	u, ok := h.contextUser(w, r)
+	if !ok {
+		return
+	}
 	articles, articlesCount, err := h.storage.SelectArticles(
 		r.Context(),
 		&postgres.SelectArticlesParams{
@@ -92,7 +101,7 @@ func (h *handler) feedArticles(w http.ResponseWriter, r *http.Request) {
 			})
 			break
 		default:
-			slog.Error(err.Error())
+			logger(r.Context()).Error(err.Error())
 			InternalServerError(w)
 			break
 		}
@@ -106,14 +115,7 @@ func (h *handler) feedArticles(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *handler) listArticle(w http.ResponseWriter, r *http.Request) {
-	var id *uint64
-	token, err := h.getHeaderToken(r.Header)
-	if err == nil {
-		user, err := h.userFromToken(token)
-		if err == nil {
-			id = &user.ID
-		}
-	}
+	id := h.viewerID(r.Context())
 
 	author := r.URL.Query().Get("author")
 	tag := r.URL.Query().Get("tag")
@@ -153,7 +155,7 @@ func (h *handler) listArticle(w http.ResponseWriter, r *http.Request) {
 			})
 			break
 		default:
-			slog.Error(err.Error())
+			logger(r.Context()).Error(err.Error())
 			InternalServerError(w)
 			break
 		}
@@ -175,14 +177,7 @@ func (h *handler) articleBySlug(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var id *uint64
-	token, err := h.getHeaderToken(r.Header)
-	if err == nil {
-		user, err := h.userFromToken(token)
-		if err == nil {
-			id = &user.ID
-		}
-	}
+	id := h.viewerID(r.Context())
 
 	article, _, err := h.storage.SelectArticles(r.Context(), &postgres.SelectArticlesParams{
 		UserID: id,
@@ -210,6 +205,41 @@ func (h *handler) articleBySlug(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// findArticle loads the article with the given slug. When there is none, or
+// the lookup fails, it writes the error response and returns nil.
+func (h *handler) findArticle(
+	w http.ResponseWriter,
+	r *http.Request,
+	slug string,
+	viewerID *uint64,
+	includeSuspended bool,
+) *entity.Article {
+	articles, _, err := h.storage.SelectArticles(r.Context(), &postgres.SelectArticlesParams{
+		UserID:           viewerID,
+		Slug:             null.StringFrom(slug),
+		Limit:            null.IntFrom(1),
+		IncludeSuspended: includeSuspended,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			NotFoundError(w)
+			break
+		default:
+			logger(r.Context()).Error(err.Error())
+			InternalServerError(w)
+			break
+		}
+		return nil
+	}
+	if len(articles) == 0 {
+		NotFoundError(w)
+		return nil
+	}
+
+	return articles[0]
+}
+
 type UpdateArticleRequestArticle struct {
 	Title       null.String `json:"title"`
 	Description null.String `json:"description"`
@@ -235,17 +265,31 @@ func (h *handler) updateArticle(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	u, ok := h.contextUser(w, r)
+	if !ok {
+		return
+	}
+	current := h.findArticle(w, r, slugField.String, &u.ID, false)
+	if current == nil {
+		return
+	}
+
+	if !policy.CanUpdateArticle(u, current) {
+		ForbiddenError(w)
+		return
+	}
+
 	var newSlug null.String
 	if body.Article.Title.Valid {
 		newSlug = null.StringFrom(slug.Make(body.Article.Title.String))
 	}
 
 	err := h.storage.UpdateArticle(r.Context(), &postgres.UpdateArticleParams{
-		OriginalSlug: slugField.String,
-		Slug:         newSlug,
-		Title:        body.Article.Title,
-		Description:  body.Article.Description,
-		Body:         body.Article.Body,
+		ID:          current.ID,
+		Slug:        newSlug,
+		Title:       body.Article.Title,
+		Description: body.Article.Description,
+		Body:        body.Article.Body,
 	})
 
 	if err != nil {
@@ -257,7 +301,6 @@ func (h *handler) updateArticle(w http.ResponseWriter, r *http.Request) {
 		slugField = newSlug
 	}
 
-	u := h.MustContextUser(r.Context())
 	article, _, err := h.storage.SelectArticles(r.Context(), &postgres.SelectArticlesParams{
 		UserID: &u.ID,
 		Slug:   slugField,
@@ -293,10 +336,25 @@ func (h *handler) deleteArticle(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	u := h.MustContextUser(r.Context())
-	err := h.storage.RemoveArticle(r.Context(), slug.String, u.ID)
+	u, ok := h.contextUser(w, r)
+	if !ok {
+		return
+	}
+	article := h.findArticle(w, r, slug.String, &u.ID, policy.CanModerate(u))
+	if article == nil {
+		return
+	}
+
+	if !policy.CanDeleteArticle(u, article) {
+		ForbiddenError(w)
+		return
+	}
+
+	// Delete the article the policy approved, even if the slug was taken
+	// over in the meantime.
+	err := h.storage.RemoveArticle(r.Context(), article.ID)
 	if err != nil {
-		slog.Error(err.Error())
+		logger(r.Context()).Error(err.Error())
 		switch {
 		case errors.Is(err, postgres.ErrNotFound):
 			NotFoundError(w)
@@ -321,7 +379,10 @@ func (h *handler) favoriteArticle(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	u := h.MustContextUser(r.Context())
+	u, ok := h.contextUser(w, r)
+	if !ok {
+		return
+	}
 	article, _, err := h.storage.SelectArticles(r.Context(), &postgres.SelectArticlesParams{
 		UserID: &u.ID,
 		Slug:   slug,
@@ -350,11 +411,13 @@ func (h *handler) favoriteArticle(w http.ResponseWriter, r *http.Request) {
 
 	err = h.storage.FavoriteArticle(r.Context(), u.ID, article[0].ID)
 	if err != nil {
-		slog.Error(err.Error())
+		logger(r.Context()).Error(err.Error())
 		InternalServerError(w)
 		return
 	}
 
+	h.metrics.ArticleFavorited()
+
 	article[0].Favorited = true
 	article[0].FavoritesCount += 1
 
@@ -372,7 +435,10 @@ func (h *handler) unfavoriteArticle(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	u := h.MustContextUser(r.Context())
+	u, ok := h.contextUser(w, r)
+	if !ok {
+		return
+	}
 	article, _, err := h.storage.SelectArticles(r.Context(), &postgres.SelectArticlesParams{
 		UserID: &u.ID,
 		Slug:   slug,
@@ -3,11 +3,10 @@ package handler
 import (
 	"database/sql"
 	"errors"
-	"fmt"
-	"log/slog"
 	"net/http"
 	"strconv"
 
+	"github.com/askerdev/realworld-clone-go/internal/policy"
 	"github.com/askerdev/realworld-clone-go/internal/postgres"
 	"github.com/guregu/null/v5"
 )
@@ -21,14 +20,7 @@ func (h *handler) listComments(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var userID *uint64
-	token, err := h.getHeaderToken(r.Header)
-	if err == nil {
-		user, err := h.userFromToken(token)
-		if err == nil {
-			userID = &user.ID
-		}
-	}
+	userID := h.viewerID(r.Context())
 
 	comments, err := h.storage.SelectComments(r.Context(), &postgres.SelectCommentsParams{
 		UserID:      userID,
@@ -42,7 +34,7 @@ func (h *handler) listComments(w http.ResponseWriter, r *http.Request) {
 			})
 			break
 		default:
-			slog.Error(err.Error())
+			logger(r.Context()).Error(err.Error())
 			InternalServerError(w)
 			break
 		}
@@ -77,7 +69,10 @@ func (h *handler) createComment(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	u := h.MustContextUser(r.Context())
+	u, ok := h.contextUser(w, r)
+	if !ok {
+		return
+	}
 
 	comment, err := h.storage.InsertComment(
 		r.Context(),
@@ -88,11 +83,20 @@ func (h *handler) createComment(w http.ResponseWriter, r *http.Request) {
 		},
 	)
 	if err != nil {
-		slog.Error(err.Error())
-		AlreayExistsError(w)
+		logger(r.Context()).Error(err.Error())
+		switch {
+		case errors.Is(err, postgres.ErrInsertedCommentNotFound):
+			InternalServerError(w)
+			break
+		default:
+			AlreayExistsError(w)
+			break
+		}
 		return
 	}
 
+	h.metrics.CommentCreated()
+
 	JSON(w, map[string]any{
 		"comment": comment,
 	})
@@ -106,7 +110,6 @@ func (h *handler) deleteComment(w http.ResponseWriter, r *http.Request) {
 	commentIDString := r.PathValue("id")
 	if len(commentIDString) > 0 {
 		commentIDInt, err := strconv.Atoi(commentIDString)
-		fmt.Println(err)
 		if err == nil && commentIDInt >= 0 {
 			commentID.Int64 = int64(commentIDInt)
 			commentID.Valid = true
@@ -118,19 +121,43 @@ func (h *handler) deleteComment(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	u := h.MustContextUser(r.Context())
+	u, ok := h.contextUser(w, r)
+	if !ok {
+		return
+	}
 
-	err := h.storage.DeleteComment(
-		r.Context(),
-		&postgres.DeleteCommentParams{
-			CommentID:   uint64(commentID.Int64),
-			ArticleSlug: slug.String,
-			UserID:      u.ID,
-		},
-	)
+	id := uint64(commentID.Int64)
+	comments, err := h.storage.SelectComments(r.Context(), &postgres.SelectCommentsParams{
+		CommentID:        &id,
+		ArticleSlug:      slug.String,
+		IncludeSuspended: policy.CanModerate(u),
+	})
 	if err != nil {
-		slog.Error(err.Error())
+		logger(r.Context()).Error(err.Error())
 		InternalServerError(w)
 		return
 	}
+	if len(comments) == 0 {
+		NotFoundError(w)
+		return
+	}
+
+	if !policy.CanDeleteComment(u, comments[0]) {
+		ForbiddenError(w)
+		return
+	}
+
+	err = h.storage.DeleteComment(r.Context(), comments[0].ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, postgres.ErrNotFound):
+			NotFoundError(w)
+			break
+		default:
+			logger(r.Context()).Error(err.Error())
+			InternalServerError(w)
+			break
+		}
+		return
+	}
 }
@@ -0,0 +1,97 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+const readinessTimeout = 2 * time.Second
+
+// Drain makes /readyz fail from now on, so load balancers stop sending
+// traffic before the server shuts down.
+func (h *handler) Drain() {
+	h.draining.Store(true)
+}
+
+type readinessCheck struct {
+	name  string
+	check func(ctx context.Context) error
+}
+
+func (h *handler) readinessChecks() []readinessCheck {
+	return []readinessCheck{
+		{"shutdown", func(context.Context) error {
+			if h.draining.Load() {
+				return errors.New("server is shutting down")
+			}
+			return nil
+		}},
+		{"database", h.storage.Ping},
+		{"migrations", func(ctx context.Context) error {
+			if h.opts.Migrator == nil {
+				return nil
+			}
+			return h.opts.Migrator.Check(ctx)
+		}},
+		{"keys", func(context.Context) error {
+			if err := h.issuer.CheckKeys(); err != nil {
+				return err
+			}
+			if len(h.validator.JWKS().Keys) == 0 {
+				return errors.New("no verification keys")
+			}
+			return nil
+		}},
+	}
+}
+
+// CheckResult is only "ok" or "fail": probes are unauthenticated and errors
+// such as a failed ping name the database host and user, so the detail only
+// goes to the log.
+type CheckResult struct {
+	Status string `json:"status"`
+}
+
+type ReadinessResponse struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks"`
+}
+
+// livez only tells the process is serving; it never looks at dependencies,
+// so a database outage doesn't get the process restarted.
+func (h *handler) livez(w http.ResponseWriter, _ *http.Request) {
+	JSON(w, map[string]any{
+		"status": "ok",
+	})
+}
+
+func (h *handler) readyz(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), readinessTimeout)
+	defer cancel()
+
+	res := ReadinessResponse{
+		Status: "ok",
+		Checks: map[string]CheckResult{},
+	}
+
+	for _, c := range h.readinessChecks() {
+		if err := c.check(ctx); err != nil {
+			logger(r.Context()).Error("readiness check failed", slog.String("check", c.name), slog.Any("error", err))
+			res.Status = "fail"
+			res.Checks[c.name] = CheckResult{Status: "fail"}
+			continue
+		}
+		res.Checks[c.name] = CheckResult{Status: "ok"}
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	if res.Status != "ok" {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+
+	JSON(w, res)
+}
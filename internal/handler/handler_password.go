@@ -0,0 +1,160 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/askerdev/realworld-clone-go/internal/domain/entity"
+	"github.com/askerdev/realworld-clone-go/internal/domain/vo"
+	"github.com/askerdev/realworld-clone-go/internal/mail"
+	"github.com/askerdev/realworld-clone-go/internal/postgres"
+	"github.com/askerdev/realworld-clone-go/pkg/simplejwt"
+)
+
+const passwordResetTokenTTL = time.Hour
+
+type ForgotPasswordRequestUser struct {
+	Email string `json:"email"`
+}
+
+type ForgotPasswordRequest struct {
+	User ForgotPasswordRequestUser `json:"user"`
+}
+
+func (h *handler) forgotPassword(w http.ResponseWriter, r *http.Request) {
+	var body ForgotPasswordRequest
+	if err := ParseBody(r.Body, &body); err != nil {
+		InvalidJSON(w)
+		return
+	}
+
+	email, err := vo.NewEmail(body.User.Email)
+	if err != nil {
+		ValidationError(w, FieldErrMap{
+			"email": {err.Error()},
+		})
+		return
+	}
+
+	u, err := h.storage.SelectUserByEmail(r.Context(), string(email))
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		logger(r.Context()).Error(err.Error())
+		InternalServerError(w)
+		return
+	}
+
+	// The response is the same whether the account exists or not, and
+	// comes as fast: the token and mail are taken care of in the
+	// background, where a failure is only logged.
+	if err == nil {
+		ctx := context.WithoutCancel(r.Context())
+		go func() {
+			if err := h.sendPasswordResetEmail(ctx, u); err != nil {
+				logger(ctx).Error(err.Error())
+			}
+		}()
+	}
+
+	JSON(w, map[string]any{
+		"message": "if the account exists, a reset link has been sent",
+	})
+}
+
+// sendPasswordResetEmail stores a fresh reset token for u and mails the
+// link to them.
+func (h *handler) sendPasswordResetEmail(ctx context.Context, u *entity.User) error {
+	token, hash, err := simplejwt.NewOpaqueToken("")
+	if err != nil {
+		return err
+	}
+
+	err = h.storage.InsertPasswordResetToken(ctx, u.ID, hash, h.now().Add(passwordResetTokenTTL))
+	if err != nil {
+		return err
+	}
+
+	err = h.mailer.Send(ctx, mail.Message{
+		To:      u.Email,
+		Subject: "Reset your Conduit password",
+		Body: "Hi " + u.Username + ",\n\n" +
+			"Use the link below to choose a new password. It expires in one hour.\n\n" +
+			h.opts.AppURL + "/reset-password?token=" + url.QueryEscape(token) + "\n\n" +
+			"If you didn't ask for a reset, you can ignore this email.\n",
+	})
+	if err != nil {
+		return fmt.Errorf("sending password reset mail: %w", err)
+	}
+
+	return nil
+}
+
+type ResetPasswordRequestUser struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+type ResetPasswordRequest struct {
+	User ResetPasswordRequestUser `json:"user"`
+}
+
+func (h *handler) resetPassword(w http.ResponseWriter, r *http.Request) {
+	var body ResetPasswordRequest
+	if err := ParseBody(r.Body, &body); err != nil {
+		InvalidJSON(w)
+		return
+	}
+
+	var errs FieldErrMap
+	if body.User.Token == "" {
+		errs.Append("token", "token is required")
+	}
+
+	password, err := vo.NewPassword(body.User.Password)
+	errs.AppendErr("password", err)
+
+	if !errs.Empty() {
+		ValidationError(w, errs)
+		return
+	}
+
+	passHash, err := password.Hash()
+	if err != nil {
+		logger(r.Context()).Error("password hashing error", slog.String("msg", err.Error()))
+		InternalServerError(w)
+		return
+	}
+
+	userID, err := h.storage.ResetPassword(r.Context(), simplejwt.HashOpaqueToken(body.User.Token), passHash, h.now())
+	if err != nil {
+		switch {
+		case errors.Is(err, postgres.ErrNotFound):
+			ValidationError(w, FieldErrMap{
+				"token": {"token is invalid or expired"},
+			})
+			break
+		default:
+			logger(r.Context()).Error(err.Error())
+			InternalServerError(w)
+			break
+		}
+		return
+	}
+
+	if err := h.issuer.RevokeAll(userID); err != nil {
+		logger(r.Context()).Error(err.Error())
+		InternalServerError(w)
+		return
+	}
+
+	h.users.Invalidate(userID)
+
+	JSON(w, map[string]any{
+		"message": "password has been reset",
+	})
+}
@@ -0,0 +1,58 @@
+package handler
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/askerdev/realworld-clone-go/pkg/ratelimit"
+	"github.com/askerdev/realworld-clone-go/pkg/router"
+	"github.com/askerdev/realworld-clone-go/pkg/simplejwt"
+)
+
+// RateLimits are the rates of the route groups. A zero rate doesn't limit.
+type RateLimits struct {
+	Public      ratelimit.Rate
+	Credentials ratelimit.Rate
+	Auth        ratelimit.Rate
+	User        ratelimit.Rate
+	Create      ratelimit.Rate
+}
+
+// rateLimit limits requests under policy. Without a store nothing is
+// limited.
+func (h *handler) rateLimit(policy string, rate ratelimit.Rate, key func(*http.Request) string) router.Middleware {
+	if h.limiter == nil {
+		return func(next http.Handler) http.Handler {
+			return next
+		}
+	}
+
+	return h.limiter.Middleware(policy, rate, key)
+}
+
+func (h *handler) clientIP(r *http.Request) string {
+	return h.realIP.ClientIP(r)
+}
+
+// ipKey counts requests per client IP.
+func (h *handler) ipKey(r *http.Request) string {
+	return "ip:" + h.clientIP(r)
+}
+
+// userKey counts requests per user, or per client IP when anonymous.
+func (h *handler) userKey(r *http.Request) string {
+	if u, err := simplejwt.ContextUser(r.Context()); err == nil {
+		return "user:" + strconv.FormatUint(u.ID, 10)
+	}
+
+	return h.ipKey(r)
+}
+
+func tooManyRequests(w http.ResponseWriter, _ *http.Request, _ ratelimit.Result) {
+	NewError("too many requests, try again later", http.StatusTooManyRequests).Write(w)
+}
+
+func rateLimitError(r *http.Request, err error) {
+	logger(r.Context()).Error("rate limit store", slog.Any("error", err))
+}
@@ -0,0 +1,257 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/askerdev/realworld-clone-go/internal/mail"
+	"github.com/askerdev/realworld-clone-go/internal/mem"
+	"github.com/askerdev/realworld-clone-go/internal/migrate"
+	"github.com/askerdev/realworld-clone-go/migrations"
+	"github.com/askerdev/realworld-clone-go/pkg/simplejwt"
+	_ "github.com/jackc/pgx/stdlib"
+	"github.com/jmoiron/sqlx"
+)
+
+// testDSNEnv names the database the handler tests run against, e.g. the
+// one from compose.yaml. The tests are skipped when it isn't set.
+const testDSNEnv = "CONDUIT_TEST_DATABASE_DSN"
+
+// testClock is a fixed clock the tests move by hand.
+type testClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func newTestClock() *testClock {
+	return &testClock{now: time.Date(2025, time.March, 1, 12, 0, 0, 0, time.UTC)}
+}
+
+func (c *testClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+func (c *testClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(d)
+}
+
+type testMailer struct{}
+
+func (testMailer) Send(context.Context, mail.Message) error {
+	return nil
+}
+
+type testServer struct {
+	t     *testing.T
+	db    *sqlx.DB
+	h     *handler
+	clock *testClock
+	// ip is the client address of every request, unique per test so
+	// login throttling doesn't carry over between tests or runs.
+	ip string
+}
+
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+
+	dsn := os.Getenv(testDSNEnv)
+	if dsn == "" {
+		t.Skip(testDSNEnv + " is not set")
+	}
+
+	db, err := sqlx.Connect("pgx", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	migrator, err := migrate.New(db, migrations.FS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrator.Up(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	_, key, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyset := simplejwt.NewKeyset()
+	if err := keyset.SetSigningKey(key); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := simplejwt.DefaultOptions()
+	cache := mem.NewJWTCache(opts.TTL + opts.Leeway)
+	issuer, err := simplejwt.NewIssuer(keyset, cache, mem.NewRefreshStore(), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	validator, err := simplejwt.NewValidator(keyset, cache, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clock := newTestClock()
+	s := &testServer{
+		t:     t,
+		db:    db,
+		clock: clock,
+		ip:    randomIP(t),
+		h: New(db, issuer, validator, testMailer{}, Options{
+			Now:    clock.Now,
+			Logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+		}),
+	}
+	t.Cleanup(func() {
+		db.Exec(`DELETE FROM login_failures WHERE key = $1`, ipLoginThrottle.key(s.ip))
+	})
+
+	return s
+}
+
+// do sends body as JSON and decodes the JSON response, if any.
+func (s *testServer) do(method, path, token string, body any) (int, map[string]any) {
+	s.t.Helper()
+
+	var buf bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&buf).Encode(body); err != nil {
+			s.t.Fatal(err)
+		}
+	}
+
+	req := httptest.NewRequest(method, path, &buf)
+	req.RemoteAddr = s.ip + ":1234"
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Token "+token)
+	}
+
+	rec := httptest.NewRecorder()
+	s.h.ServeHTTP(rec, req)
+
+	res := map[string]any{}
+	if rec.Body.Len() > 0 {
+		if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+			s.t.Fatalf("%s %s: decoding %q: %v", method, path, rec.Body.String(), err)
+		}
+	}
+
+	return rec.Code, res
+}
+
+// mustDo is do for requests expected to answer with status.
+func (s *testServer) mustDo(status int, method, path, token string, body any) map[string]any {
+	s.t.Helper()
+
+	code, res := s.do(method, path, token, body)
+	if code != status {
+		s.t.Fatalf("%s %s = %d %v, want %d", method, path, code, res, status)
+	}
+
+	return res
+}
+
+type testUser struct {
+	email    string
+	password string
+}
+
+// register creates a user with a unique email and cleans up its login
+// failures afterwards.
+func (s *testServer) register() testUser {
+	s.t.Helper()
+
+	suffix := randomHex(s.t, 6)
+	u := testUser{
+		email:    "user-" + suffix + "@example.com",
+		password: "password-" + suffix,
+	}
+	s.mustDo(http.StatusOK, http.MethodPost, "/api/users", "", map[string]any{
+		"user": map[string]any{
+			"email":    u.email,
+			"username": "user-" + suffix,
+			"password": u.password,
+		},
+	})
+	s.t.Cleanup(func() {
+		s.db.Exec(`DELETE FROM login_failures WHERE key = $1`, accountLoginThrottle.key(u.email))
+	})
+
+	return u
+}
+
+func (s *testServer) login(u testUser) (int, map[string]any) {
+	s.t.Helper()
+
+	return s.do(http.MethodPost, "/api/users/login", "", map[string]any{
+		"user": map[string]any{
+			"email":    u.email,
+			"password": u.password,
+		},
+	})
+}
+
+// field walks res along keys, failing the test when a key is missing.
+func field[T any](t *testing.T, res map[string]any, keys ...string) T {
+	t.Helper()
+
+	var v any = res
+	for _, key := range keys {
+		m, ok := v.(map[string]any)
+		if !ok {
+			t.Fatalf("%v has no %q", v, key)
+		}
+		v = m[key]
+	}
+
+	value, ok := v.(T)
+	if !ok {
+		t.Fatalf("%v at %v is %T", v, keys, v)
+	}
+
+	return value
+}
+
+func randomHex(t *testing.T, n int) string {
+	t.Helper()
+
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		t.Fatal(err)
+	}
+
+	return hex.EncodeToString(b)
+}
+
+func randomIP(t *testing.T) string {
+	t.Helper()
+
+	b := make([]byte, 3)
+	if _, err := rand.Read(b); err != nil {
+		t.Fatal(err)
+	}
+
+	return netip.AddrFrom4([4]byte{10, b[0], b[1], b[2]}).String()
+}
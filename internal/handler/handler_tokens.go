@@ -0,0 +1,164 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/askerdev/realworld-clone-go/internal/postgres"
+	"github.com/askerdev/realworld-clone-go/pkg/simplejwt"
+	"github.com/guregu/null/v5"
+)
+
+const (
+	scopeReadOnly       = "read-only"
+	scopeArticlesWrite  = "articles:write"
+	scopeFavoritesWrite = "favorites:write"
+	scopeCommentsWrite  = "comments:write"
+	scopeProfilesWrite  = "profiles:write"
+	// scopeUserWrite covers the profile only; email and password need a
+	// login session.
+	scopeUserWrite = "user:write"
+	// scopeSession is never granted to personal access tokens, so routes
+	// requiring it are only reachable with a login session.
+	scopeSession = "session"
+)
+
+var tokenScopes = []string{
+	scopeReadOnly,
+	scopeArticlesWrite,
+	scopeFavoritesWrite,
+	scopeCommentsWrite,
+	scopeProfilesWrite,
+	scopeUserWrite,
+}
+
+type CreateTokenRequestToken struct {
+	Name      string    `json:"name"`
+	Scopes    []string  `json:"scopes"`
+	ExpiresAt null.Time `json:"expiresAt"`
+}
+
+type CreateTokenRequest struct {
+	Token CreateTokenRequestToken `json:"token"`
+}
+
+func (h *handler) listTokens(w http.ResponseWriter, r *http.Request) {
+	u, ok := h.contextUser(w, r)
+	if !ok {
+		return
+	}
+	tokens, err := h.storage.SelectPersonalAccessTokens(r.Context(), u.ID)
+	if err != nil {
+		logger(r.Context()).Error(err.Error())
+		InternalServerError(w)
+		return
+	}
+
+	JSON(w, map[string]any{
+		"tokens": tokens,
+	})
+}
+
+func (h *handler) createToken(w http.ResponseWriter, r *http.Request) {
+	var body CreateTokenRequest
+	if err := ParseBody(r.Body, &body); err != nil {
+		InvalidJSON(w)
+		return
+	}
+
+	var errs FieldErrMap
+	if len(body.Token.Name) < 1 || len(body.Token.Name) > 64 {
+		errs.Append("name", "name length is invalid")
+	}
+
+	if len(body.Token.Scopes) == 0 {
+		errs.Append("scopes", "at least one scope is required")
+	}
+	for _, scope := range body.Token.Scopes {
+		if !slices.Contains(tokenScopes, scope) {
+			errs.Append("scopes", "unknown scope "+scope)
+		}
+	}
+	if slices.Contains(body.Token.Scopes, scopeReadOnly) && len(body.Token.Scopes) > 1 {
+		errs.Append("scopes", "read-only can not be combined with other scopes")
+	}
+
+	if body.Token.ExpiresAt.Valid && !body.Token.ExpiresAt.Time.After(time.Now()) {
+		errs.Append("expiresAt", "expiration must be in the future")
+	}
+
+	if !errs.Empty() {
+		ValidationError(w, errs)
+		return
+	}
+
+	tokenString, hash, err := simplejwt.NewPersonalAccessToken()
+	if err != nil {
+		logger(r.Context()).Error(err.Error())
+		InternalServerError(w)
+		return
+	}
+
+	u, ok := h.contextUser(w, r)
+	if !ok {
+		return
+	}
+	token, err := h.storage.InsertPersonalAccessToken(r.Context(), &postgres.InsertPersonalAccessTokenParams{
+		UserID:    u.ID,
+		Name:      body.Token.Name,
+		TokenHash: hash,
+		Scopes:    slices.Compact(slices.Sorted(slices.Values(body.Token.Scopes))),
+		ExpiresAt: body.Token.ExpiresAt,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, postgres.ErrUniqueConstraint):
+			ValidationError(w, FieldErrMap{
+				"name": {"token with this name already exists"},
+			})
+			break
+		default:
+			logger(r.Context()).Error(err.Error())
+			InternalServerError(w)
+			break
+		}
+		return
+	}
+
+	token.Token = &tokenString
+
+	JSON(w, map[string]any{
+		"token": token,
+	})
+}
+
+func (h *handler) deleteToken(w http.ResponseWriter, r *http.Request) {
+	tokenID, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
+	if err != nil {
+		NotFoundError(w)
+		return
+	}
+
+	u, ok := h.contextUser(w, r)
+	if !ok {
+		return
+	}
+	err = h.storage.DeletePersonalAccessToken(r.Context(), u.ID, tokenID)
+	if err != nil {
+		switch {
+		case errors.Is(err, postgres.ErrNotFound):
+			NotFoundError(w)
+			break
+		default:
+			logger(r.Context()).Error(err.Error())
+			InternalServerError(w)
+			break
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,367 @@
+package handler
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base32"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/askerdev/realworld-clone-go/internal/domain/entity"
+	"github.com/askerdev/realworld-clone-go/internal/domain/vo"
+	"github.com/askerdev/realworld-clone-go/internal/postgres"
+	"github.com/askerdev/realworld-clone-go/pkg/simplejwt"
+	"github.com/askerdev/realworld-clone-go/pkg/totp"
+)
+
+const (
+	totpIssuer                = "Conduit"
+	totpSkew                  = 1
+	recoveryCodesCount        = 10
+	twoFactorChallengeTTL     = 5 * time.Minute
+	twoFactorChallengeRetries = 5
+)
+
+var recoveryCodeEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+func newRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, 0, recoveryCodesCount)
+	hashes := make([]string, 0, recoveryCodesCount)
+	for range recoveryCodesCount {
+		b := make([]byte, 10)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+		code := strings.ToLower(recoveryCodeEncoding.EncodeToString(b)[:10])
+		codes = append(codes, code[:5]+"-"+code[5:])
+		hashes = append(hashes, simplejwt.HashOpaqueToken(code))
+	}
+
+	return codes, hashes, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	code = strings.ReplaceAll(code, "-", "")
+	return strings.ReplaceAll(code, " ", "")
+}
+
+func (h *handler) twoFactorEnabled(ctx context.Context, userID uint64) (bool, error) {
+	t, err := h.storage.SelectTOTP(ctx, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return t.EnabledAt.Valid, nil
+}
+
+// checkSecondFactor accepts either a current TOTP code or an unused
+// recovery code. Both are single-use.
+func (h *handler) checkSecondFactor(
+	ctx context.Context,
+	userID uint64,
+	code string,
+	recoveryCode string,
+) (bool, error) {
+	if recoveryCode != "" {
+		hash := simplejwt.HashOpaqueToken(normalizeRecoveryCode(recoveryCode))
+		return h.storage.UseRecoveryCode(ctx, userID, hash)
+	}
+
+	t, err := h.storage.SelectTOTP(ctx, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	if !t.EnabledAt.Valid {
+		return false, nil
+	}
+
+	step, ok := totp.Validate(t.Secret, code, h.now(), totpSkew)
+	if !ok {
+		return false, nil
+	}
+
+	return h.storage.UseTOTPStep(ctx, userID, step)
+}
+
+func (h *handler) startTwoFactorChallenge(w http.ResponseWriter, r *http.Request, u *entity.User) {
+	token, hash, err := simplejwt.NewOpaqueToken("")
+	if err != nil {
+		logger(r.Context()).Error(err.Error())
+		InternalServerError(w)
+		return
+	}
+
+	expiresAt := h.now().Add(twoFactorChallengeTTL)
+	if err := h.storage.InsertTwoFactorChallenge(r.Context(), u.ID, hash, expiresAt); err != nil {
+		logger(r.Context()).Error(err.Error())
+		InternalServerError(w)
+		return
+	}
+
+	JSON(w, map[string]any{
+		"challenge": map[string]any{
+			"token":     token,
+			"expiresAt": expiresAt,
+		},
+	})
+}
+
+type TwoFactorLoginRequest struct {
+	Challenge    string `json:"challenge"`
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recoveryCode"`
+}
+
+func (h *handler) loginTwoFactor(w http.ResponseWriter, r *http.Request) {
+	var body TwoFactorLoginRequest
+	if err := ParseBody(r.Body, &body); err != nil {
+		InvalidJSON(w)
+		return
+	}
+
+	if body.Challenge == "" || (body.Code == "" && body.RecoveryCode == "") {
+		ValidationError(w, FieldErrMap{
+			"code": {"challenge and code or recovery code are required"},
+		})
+		return
+	}
+
+	hash := simplejwt.HashOpaqueToken(body.Challenge)
+	userID, err := h.storage.AttemptTwoFactorChallenge(r.Context(), hash, twoFactorChallengeRetries, h.now())
+	if err != nil {
+		switch {
+		case errors.Is(err, postgres.ErrNotFound):
+			UnauthorizedError(w)
+			break
+		default:
+			logger(r.Context()).Error(err.Error())
+			InternalServerError(w)
+			break
+		}
+		return
+	}
+
+	u, err := h.storage.SelectUserByID(r.Context(), userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			// the user was deleted while the challenge was pending
+			UnauthorizedError(w)
+			break
+		default:
+			logger(r.Context()).Error(err.Error())
+			InternalServerError(w)
+			break
+		}
+		return
+	}
+
+	// Wrong codes count against the account like wrong passwords, so new
+	// challenges don't buy more guesses.
+	email := vo.Email(u.Email)
+	if h.secondFactorLocked(w, r, email) {
+		return
+	}
+
+	ok, err := h.checkSecondFactor(r.Context(), userID, body.Code, body.RecoveryCode)
+	if err != nil {
+		logger(r.Context()).Error(err.Error())
+		InternalServerError(w)
+		return
+	}
+	if !ok {
+		h.secondFactorFailed(w, r, email, userID)
+		return
+	}
+
+	if err := h.storage.DeleteTwoFactorChallenge(r.Context(), hash, h.now()); err != nil {
+		logger(r.Context()).Error(err.Error())
+	}
+
+	h.clearLoginFailures(r.Context(), email)
+	h.writeSession(w, r, u)
+}
+
+func (h *handler) enrollTwoFactor(w http.ResponseWriter, r *http.Request) {
+	u, ok := h.contextUser(w, r)
+	if !ok {
+		return
+	}
+
+	secret, err := totp.NewSecret()
+	if err != nil {
+		logger(r.Context()).Error(err.Error())
+		InternalServerError(w)
+		return
+	}
+
+	err = h.storage.SavePendingTOTP(r.Context(), u.ID, secret)
+	if err != nil {
+		switch {
+		case errors.Is(err, postgres.ErrUniqueConstraint):
+			NewError("two-factor authentication is already enabled", http.StatusBadRequest).Write(w)
+			break
+		default:
+			logger(r.Context()).Error(err.Error())
+			InternalServerError(w)
+			break
+		}
+		return
+	}
+
+	JSON(w, map[string]any{
+		"twoFactor": map[string]any{
+			"secret": secret,
+			"uri":    totp.URI(totpIssuer, u.Email, secret),
+		},
+	})
+}
+
+type TwoFactorCodeRequest struct {
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recoveryCode"`
+}
+
+func (h *handler) confirmTwoFactor(w http.ResponseWriter, r *http.Request) {
+	var body TwoFactorCodeRequest
+	if err := ParseBody(r.Body, &body); err != nil {
+		InvalidJSON(w)
+		return
+	}
+
+	u, ok := h.contextUser(w, r)
+	if !ok {
+		return
+	}
+	t, err := h.storage.SelectTOTP(r.Context(), u.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			NotFoundError(w)
+			break
+		default:
+			logger(r.Context()).Error(err.Error())
+			InternalServerError(w)
+			break
+		}
+		return
+	}
+
+	if t.EnabledAt.Valid {
+		NewError("two-factor authentication is already enabled", http.StatusBadRequest).Write(w)
+		return
+	}
+
+	email := vo.Email(u.Email)
+	if h.secondFactorLocked(w, r, email) {
+		return
+	}
+
+	step, ok := totp.Validate(t.Secret, body.Code, h.now(), totpSkew)
+	if !ok {
+		h.secondFactorFailed(w, r, email, u.ID)
+		return
+	}
+	h.clearLoginFailures(r.Context(), email)
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		logger(r.Context()).Error(err.Error())
+		InternalServerError(w)
+		return
+	}
+
+	if err := h.storage.EnableTOTP(r.Context(), u.ID, step, hashes); err != nil {
+		logger(r.Context()).Error(err.Error())
+		InternalServerError(w)
+		return
+	}
+
+	JSON(w, map[string]any{
+		"twoFactor": map[string]any{
+			"enabled":       true,
+			"recoveryCodes": codes,
+		},
+	})
+}
+
+func (h *handler) disableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	var body TwoFactorCodeRequest
+	if err := ParseBody(r.Body, &body); err != nil {
+		InvalidJSON(w)
+		return
+	}
+
+	u, ok := h.contextUser(w, r)
+	if !ok {
+		return
+	}
+
+	email := vo.Email(u.Email)
+	if h.secondFactorLocked(w, r, email) {
+		return
+	}
+
+	ok, err := h.checkSecondFactor(r.Context(), u.ID, body.Code, body.RecoveryCode)
+	if err != nil {
+		logger(r.Context()).Error(err.Error())
+		InternalServerError(w)
+		return
+	}
+	if !ok {
+		h.secondFactorFailed(w, r, email, u.ID)
+		return
+	}
+	h.clearLoginFailures(r.Context(), email)
+
+	if err := h.storage.DisableTOTP(r.Context(), u.ID); err != nil {
+		logger(r.Context()).Error(err.Error())
+		InternalServerError(w)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// secondFactorLocked answers 429 and reports true while the account or the
+// client IP is locked out. Every endpoint that checks a code shares the
+// login lockout, so a session doesn't buy unlimited guesses either.
+func (h *handler) secondFactorLocked(w http.ResponseWriter, r *http.Request, email vo.Email) bool {
+	lockedUntil, locked, err := h.loginLockedUntil(r.Context(), email, h.clientIP(r))
+	if err != nil {
+		logger(r.Context()).Error(err.Error())
+		InternalServerError(w)
+		return true
+	}
+	if locked {
+		tooManyLoginAttempts(w, lockedUntil.Sub(h.now()))
+		return true
+	}
+
+	return false
+}
+
+// secondFactorFailed counts a wrong code like a wrong password and answers
+// 422.
+func (h *handler) secondFactorFailed(w http.ResponseWriter, r *http.Request, email vo.Email, userID uint64) {
+	if err := h.loginFailed(r.Context(), email, h.clientIP(r), &userID); err != nil {
+		logger(r.Context()).Error(err.Error())
+		InternalServerError(w)
+		return
+	}
+
+	ValidationError(w, FieldErrMap{
+		"code": {"invalid code"},
+	})
+}
@@ -0,0 +1,232 @@
+package handler
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/askerdev/realworld-clone-go/pkg/totp"
+)
+
+// enableTwoFactor logs u in, enrolls and confirms TOTP, and returns the
+// secret and recovery codes.
+func (s *testServer) enableTwoFactor(u testUser) (string, []any) {
+	s.t.Helper()
+
+	code, res := s.login(u)
+	if code != http.StatusOK {
+		s.t.Fatalf("login = %d %v", code, res)
+	}
+	token := field[string](s.t, res, "user", "token")
+
+	res = s.mustDo(http.StatusOK, http.MethodPost, "/api/user/2fa", token, nil)
+	secret := field[string](s.t, res, "twoFactor", "secret")
+
+	s.mustDo(http.StatusUnprocessableEntity, http.MethodPost, "/api/user/2fa/confirm", token, map[string]any{
+		"code": s.wrongCode(secret),
+	})
+
+	confirmCode, err := totp.Code(secret, s.clock.Now())
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	res = s.mustDo(http.StatusOK, http.MethodPost, "/api/user/2fa/confirm", token, map[string]any{
+		"code": confirmCode,
+	})
+	recoveryCodes := field[[]any](s.t, res, "twoFactor", "recoveryCodes")
+	if len(recoveryCodes) != recoveryCodesCount {
+		s.t.Fatalf("got %d recovery codes, want %d", len(recoveryCodes), recoveryCodesCount)
+	}
+
+	return secret, recoveryCodes
+}
+
+// challenge logs u in with the password and returns the 2FA challenge.
+func (s *testServer) challenge(u testUser) string {
+	s.t.Helper()
+
+	code, res := s.login(u)
+	if code != http.StatusOK {
+		s.t.Fatalf("login = %d %v", code, res)
+	}
+	if _, ok := res["user"]; ok {
+		s.t.Fatal("login with 2FA enabled returned a session")
+	}
+
+	return field[string](s.t, res, "challenge", "token")
+}
+
+func (s *testServer) loginTwoFactor(challenge string, body map[string]any) (int, map[string]any) {
+	s.t.Helper()
+
+	body["challenge"] = challenge
+	return s.do(http.MethodPost, "/api/users/login/2fa", "", body)
+}
+
+// wrongCode returns a code that isn't valid for secret on the test clock.
+func (s *testServer) wrongCode(secret string) string {
+	s.t.Helper()
+
+	for _, code := range []string{"000000", "111111", "222222"} {
+		if _, ok := totp.Validate(secret, code, s.clock.Now(), totpSkew); !ok {
+			return code
+		}
+	}
+
+	s.t.Fatal("no wrong code found")
+	return ""
+}
+
+func mustCode(t *testing.T, secret string, now time.Time) string {
+	t.Helper()
+
+	code, err := totp.Code(secret, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return code
+}
+
+func TestTwoFactorLogin(t *testing.T) {
+	s := newTestServer(t)
+	u := s.register()
+	secret, recoveryCodes := s.enableTwoFactor(u)
+
+	// The code used to confirm enrollment can't be replayed to log in.
+	replayed, err := totp.Code(secret, s.clock.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, res := s.loginTwoFactor(s.challenge(u), map[string]any{"code": replayed})
+	if code != http.StatusUnprocessableEntity {
+		t.Fatalf("replayed code = %d %v, want 422", code, res)
+	}
+
+	s.clock.Advance(totp.Period)
+	next, err := totp.Code(secret, s.clock.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	challenge := s.challenge(u)
+	code, res = s.loginTwoFactor(challenge, map[string]any{"code": next})
+	if code != http.StatusOK {
+		t.Fatalf("login/2fa = %d %v, want 200", code, res)
+	}
+	if field[string](t, res, "user", "token") == "" {
+		t.Fatal("login/2fa returned no token")
+	}
+
+	// A used challenge is gone.
+	code, _ = s.loginTwoFactor(challenge, map[string]any{"code": next})
+	if code != http.StatusUnauthorized {
+		t.Fatalf("reused challenge = %d, want 401", code)
+	}
+
+	// Recovery codes work once.
+	recovery := recoveryCodes[0].(string)
+	code, res = s.loginTwoFactor(s.challenge(u), map[string]any{"recoveryCode": recovery})
+	if code != http.StatusOK {
+		t.Fatalf("recovery code = %d %v, want 200", code, res)
+	}
+	code, _ = s.loginTwoFactor(s.challenge(u), map[string]any{"recoveryCode": recovery})
+	if code != http.StatusUnprocessableEntity {
+		t.Fatalf("reused recovery code = %d, want 422", code)
+	}
+}
+
+func TestTwoFactorChallengeExpires(t *testing.T) {
+	s := newTestServer(t)
+	u := s.register()
+	secret, _ := s.enableTwoFactor(u)
+
+	challenge := s.challenge(u)
+	s.clock.Advance(twoFactorChallengeTTL + totp.Period)
+
+	code, err := totp.Code(secret, s.clock.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	status, res := s.loginTwoFactor(challenge, map[string]any{"code": code})
+	if status != http.StatusUnauthorized {
+		t.Fatalf("expired challenge = %d %v, want 401", status, res)
+	}
+}
+
+func TestTwoFactorChallengeRetries(t *testing.T) {
+	s := newTestServer(t)
+	u := s.register()
+	secret, _ := s.enableTwoFactor(u)
+	s.clock.Advance(totp.Period)
+
+	challenge := s.challenge(u)
+	for range twoFactorChallengeRetries {
+		s.loginTwoFactor(challenge, map[string]any{"code": s.wrongCode(secret)})
+	}
+
+	code, err := totp.Code(secret, s.clock.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	status, _ := s.loginTwoFactor(challenge, map[string]any{"code": code})
+	if status != http.StatusUnauthorized {
+		t.Fatalf("exhausted challenge = %d, want 401", status)
+	}
+}
+
+func TestTwoFactorFailuresLockAccount(t *testing.T) {
+	s := newTestServer(t)
+	u := s.register()
+	secret, _ := s.enableTwoFactor(u)
+	s.clock.Advance(totp.Period)
+
+	// A fresh challenge per guess must not reset the count: the password
+	// is right every time, but the failures add up on the account.
+	for i := range accountLoginThrottle.threshold {
+		status, res := s.loginTwoFactor(s.challenge(u), map[string]any{"code": s.wrongCode(secret)})
+		if status != http.StatusUnprocessableEntity {
+			t.Fatalf("guess %d = %d %v, want 422", i, status, res)
+		}
+	}
+
+	status, _ := s.login(u)
+	if status != http.StatusTooManyRequests {
+		t.Fatalf("login after failed codes = %d, want 429", status)
+	}
+
+	// The lockout runs on the injected clock too.
+	s.clock.Advance(accountLoginThrottle.lockoutDuration(accountLoginThrottle.threshold) + totp.Period)
+	code, err := totp.Code(secret, s.clock.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	status, res := s.loginTwoFactor(s.challenge(u), map[string]any{"code": code})
+	if status != http.StatusOK {
+		t.Fatalf("login/2fa after lockout = %d %v, want 200", status, res)
+	}
+}
+
+func TestTwoFactorDisableFailuresLockAccount(t *testing.T) {
+	s := newTestServer(t)
+	u := s.register()
+	secret, _ := s.enableTwoFactor(u)
+	s.clock.Advance(totp.Period)
+
+	code, res := s.loginTwoFactor(s.challenge(u), map[string]any{"code": mustCode(t, secret, s.clock.Now())})
+	if code != http.StatusOK {
+		t.Fatalf("login/2fa = %d %v", code, res)
+	}
+	token := field[string](t, res, "user", "token")
+
+	// A session must not buy unlimited guesses at the code.
+	for range accountLoginThrottle.threshold {
+		s.mustDo(http.StatusUnprocessableEntity, http.MethodDelete, "/api/user/2fa", token, map[string]any{
+			"code": s.wrongCode(secret),
+		})
+	}
+
+	s.clock.Advance(totp.Period)
+	s.mustDo(http.StatusTooManyRequests, http.MethodDelete, "/api/user/2fa", token, map[string]any{
+		"code": mustCode(t, secret, s.clock.Now()),
+	})
+}
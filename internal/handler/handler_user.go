@@ -1,13 +1,17 @@
 package handler
 
 import (
+	"context"
 	"database/sql"
 	"errors"
+	"io"
 	"log/slog"
 	"net/http"
 
+	"github.com/askerdev/realworld-clone-go/internal/domain/entity"
 	"github.com/askerdev/realworld-clone-go/internal/domain/vo"
 	"github.com/askerdev/realworld-clone-go/internal/postgres"
+	"github.com/askerdev/realworld-clone-go/pkg/simplejwt"
 	"github.com/guregu/null/v5"
 )
 
@@ -45,7 +49,7 @@ func (h *handler) register(w http.ResponseWriter, r *http.Request) {
 
 	passHash, err := password.Hash()
 	if err != nil {
-		slog.Error("password hashing error", slog.String("msg", err.Error()))
+		logger(r.Context()).Error("password hashing error", slog.String("msg", err.Error()))
 		InternalServerError(w)
 		return
 	}
@@ -62,7 +66,7 @@ func (h *handler) register(w http.ResponseWriter, r *http.Request) {
 			break
 		default:
 			{
-				slog.Error("inserting user", slog.String("msg", err.Error()))
+				logger(r.Context()).Error("inserting user", slog.String("msg", err.Error()))
 				InternalServerError(w)
 			}
 			break
@@ -70,6 +74,12 @@ func (h *handler) register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	h.metrics.UserRegistered()
+
+	if err := h.sendVerificationEmail(r.Context(), u); err != nil {
+		logger(r.Context()).Error("sending verification mail", slog.String("msg", err.Error()))
+	}
+
 	JSON(w, map[string]any{
 		"user": u,
 	})
@@ -103,44 +113,243 @@ func (h *handler) login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	ip := h.clientIP(r)
+	lockedUntil, locked, err := h.loginLockedUntil(r.Context(), email, ip)
+	if err != nil {
+		logger(r.Context()).Error(err.Error())
+		InternalServerError(w)
+		return
+	}
+	if locked {
+		tooManyLoginAttempts(w, lockedUntil.Sub(h.now()))
+		return
+	}
+
 	u, err := h.storage.SelectUserByEmail(r.Context(), string(email))
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		logger(r.Context()).Error(err.Error())
+		InternalServerError(w)
+		return
+	}
+
+	if u == nil || !password.Compare(u.Password) {
+		var userID *uint64
+		if u != nil {
+			userID = &u.ID
+		} else {
+			password.Compare(dummyPasswordHash())
+		}
+
+		if err := h.loginFailed(r.Context(), email, ip, userID); err != nil {
+			logger(r.Context()).Error(err.Error())
+			InternalServerError(w)
+			return
+		}
+
+		invalidCredentials(w)
+		return
+	}
+
+	if password.NeedsRehash(u.Password) {
+		h.rehashPassword(r.Context(), u, password)
+	}
+
+	twoFactor, err := h.twoFactorEnabled(r.Context(), u.ID)
+	if err != nil {
+		logger(r.Context()).Error(err.Error())
+		InternalServerError(w)
+		return
+	}
+
+	// With two factors, failures are only cleared once the second one
+	// passed; clearing them here would hand whoever knows the password
+	// fresh challenges without end.
+	if twoFactor {
+		h.startTwoFactorChallenge(w, r, u)
+		return
+	}
+
+	h.clearLoginFailures(r.Context(), email)
+	h.writeSession(w, r, u)
+}
+
+// rehashPassword upgrades a hash made with an older algorithm or cost. It
+// runs right after a successful compare, the only time the plain password
+// is known; a failure is logged and the login goes on.
+func (h *handler) rehashPassword(ctx context.Context, u *entity.User, password vo.Password) {
+	hash, err := password.Hash()
+	if err != nil {
+		logger(ctx).Error(err.Error())
+		return
+	}
+
+	if err := h.storage.RehashPassword(ctx, u.ID, u.Password, hash); err != nil {
+		logger(ctx).Error(err.Error())
+		return
+	}
+
+	u.Password = hash
+	h.users.Invalidate(u.ID)
+}
+
+// writeSession issues a fresh access and refresh token pair for u and
+// writes the user response.
+func (h *handler) writeSession(w http.ResponseWriter, r *http.Request, u *entity.User) {
+	if u.Suspended() {
+		accountSuspended(w)
+		return
+	}
+
+	token, err := h.issuer.Token(u.ID)
+	if err != nil {
+		logger(r.Context()).Error(err.Error())
+		InternalServerError(w)
+		return
+	}
+
+	refreshToken, err := h.issuer.RefreshToken(u.ID)
+	if err != nil {
+		logger(r.Context()).Error(err.Error())
+		InternalServerError(w)
+		return
+	}
+
+	if err := h.storage.TouchLastLogin(r.Context(), u.ID); err != nil {
+		logger(r.Context()).Error(err.Error())
+	}
+
+	u.Token = &token
+	u.RefreshToken = &refreshToken
+
+	JSON(w, map[string]any{
+		"user": u,
+	})
+}
+
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refreshToken"`
+}
+
+func (h *handler) refreshToken(w http.ResponseWriter, r *http.Request) {
+	var body RefreshTokenRequest
+	if err := ParseBody(r.Body, &body); err != nil {
+		InvalidJSON(w)
+		return
+	}
+
+	if body.RefreshToken == "" {
+		ValidationError(w, FieldErrMap{
+			"refreshToken": {"refresh token is required"},
+		})
+		return
+	}
+
+	userID, refreshToken, err := h.issuer.Rotate(body.RefreshToken)
 	if err != nil {
 		switch {
-		case errors.Is(err, sql.ErrNoRows):
-			ValidationError(w, FieldErrMap{
-				"email": {"email does not exists"},
-			})
+		case errors.Is(err, simplejwt.ErrInvalidRefreshToken),
+			errors.Is(err, simplejwt.ErrRefreshTokenReused):
+			UnauthorizedError(w)
 			break
 		default:
+			logger(r.Context()).Error(err.Error())
 			InternalServerError(w)
 			break
 		}
 		return
 	}
 
-	if !password.Compare(u.Password) {
-		ValidationError(w, FieldErrMap{
-			"password": {"invalid password"},
-		})
+	u, err := h.storage.SelectActiveUserByID(r.Context(), userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			UnauthorizedError(w)
+			break
+		default:
+			logger(r.Context()).Error(err.Error())
+			InternalServerError(w)
+			break
+		}
 		return
 	}
 
-	token, err := h.issuer.Token(u.ID, u)
+	token, err := h.issuer.Token(u.ID)
 	if err != nil {
+		logger(r.Context()).Error(err.Error())
 		InternalServerError(w)
 		return
 	}
 
 	u.Token = &token
+	u.RefreshToken = &refreshToken
 
 	JSON(w, map[string]any{
 		"user": u,
 	})
 }
 
+type LogoutRequest struct {
+	RefreshToken string `json:"refreshToken"`
+}
+
+func (h *handler) logout(w http.ResponseWriter, r *http.Request) {
+	u, ok := h.contextUser(w, r)
+	if !ok {
+		return
+	}
+
+	var body LogoutRequest
+	if err := ParseBody(r.Body, &body); err != nil && !errors.Is(err, io.EOF) {
+		InvalidJSON(w)
+		return
+	}
+
+	token, err := simplejwt.ContextToken(r.Context())
+	if err != nil {
+		logger(r.Context()).Error(err.Error())
+		UnauthorizedError(w)
+		return
+	}
+
+	if err := h.issuer.Revoke(token); err != nil {
+		logger(r.Context()).Error(err.Error())
+		InternalServerError(w)
+		return
+	}
+
+	if body.RefreshToken != "" {
+		if err := h.issuer.RevokeRefreshToken(u.ID, body.RefreshToken); err != nil {
+			logger(r.Context()).Error(err.Error())
+			InternalServerError(w)
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *handler) logoutAll(w http.ResponseWriter, r *http.Request) {
+	u, ok := h.contextUser(w, r)
+	if !ok {
+		return
+	}
+	if err := h.issuer.RevokeAll(u.ID); err != nil {
+		logger(r.Context()).Error(err.Error())
+		InternalServerError(w)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
 func (h *handler) user(w http.ResponseWriter, r *http.Request) {
+	u, ok := h.contextUser(w, r)
+	if !ok {
+		return
+	}
+
 	JSON(w, map[string]any{
-		"user": h.MustContextUser(r.Context()),
+		"user": u,
 	})
 }
 
@@ -163,6 +372,14 @@ func (h *handler) updateUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// A leaked personal access token must not be enough to take the
+	// account over.
+	if (body.User.Email.Valid || body.User.Password.Valid) &&
+		simplejwt.ContextPersonalAccessToken(r.Context()) != nil {
+		NewError("email and password can only be changed from a login session", http.StatusForbidden).Write(w)
+		return
+	}
+
 	if body.User.Password.Valid {
 		var err error
 		body.User.Password.String, err = vo.Password(body.User.Password.String).Hash()
@@ -172,7 +389,10 @@ func (h *handler) updateUser(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	u := h.MustContextUser(r.Context())
+	u, ok := h.contextUser(w, r)
+	if !ok {
+		return
+	}
 	updatedUser, err := h.storage.UpdateUser(r.Context(), &postgres.UpdateUserParams{
 		ID:       u.ID,
 		Email:    body.User.Email,
@@ -182,29 +402,30 @@ func (h *handler) updateUser(w http.ResponseWriter, r *http.Request) {
 		Bio:      body.User.Bio,
 	})
 	if err != nil && !errors.Is(err, sql.ErrNoRows) {
-		slog.Error(err.Error())
+		logger(r.Context()).Error(err.Error())
 		InternalServerError(w)
 		return
 	}
 	if errors.Is(err, sql.ErrNoRows) {
-		slog.Error(err.Error())
+		logger(r.Context()).Error(err.Error())
 		updatedUser = u
 	}
 
+	h.users.Invalidate(u.ID)
+
+	if updatedUser.Email != "" && updatedUser.Email != u.Email {
+		if err := h.sendVerificationEmail(r.Context(), updatedUser); err != nil {
+			logger(r.Context()).Error("sending verification mail", slog.String("msg", err.Error()))
+		}
+	}
+
 	JSON(w, map[string]any{
 		"user": updatedUser,
 	})
 }
 
 func (h *handler) profile(w http.ResponseWriter, r *http.Request) {
-	var id *uint64
-	token, err := h.getHeaderToken(r.Header)
-	if err == nil {
-		user, err := h.userFromToken(token)
-		if err == nil {
-			id = &user.ID
-		}
-	}
+	id := h.viewerID(r.Context())
 
 	username := r.PathValue("username")
 	if username == "" {
@@ -237,7 +458,10 @@ func (h *handler) follow(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	u := h.MustContextUser(r.Context())
+	u, ok := h.contextUser(w, r)
+	if !ok {
+		return
+	}
 
 	if username == u.Username {
 		InternalServerError(w)
@@ -260,6 +484,8 @@ func (h *handler) follow(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	h.metrics.ProfileFollowed()
+
 	JSON(w, map[string]any{
 		"profile": profile,
 	})
@@ -272,7 +498,10 @@ func (h *handler) unfollow(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	u := h.MustContextUser(r.Context())
+	u, ok := h.contextUser(w, r)
+	if !ok {
+		return
+	}
 
 	if username == u.Username {
 		InternalServerError(w)
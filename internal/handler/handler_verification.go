@@ -0,0 +1,148 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/askerdev/realworld-clone-go/internal/domain/entity"
+	"github.com/askerdev/realworld-clone-go/internal/mail"
+	"github.com/askerdev/realworld-clone-go/internal/postgres"
+	"github.com/askerdev/realworld-clone-go/pkg/simplejwt"
+)
+
+const (
+	emailVerificationTokenTTL = 24 * time.Hour
+	emailVerificationResend   = 2 * time.Minute
+)
+
+func (h *handler) sendVerificationEmail(ctx context.Context, u *entity.User) error {
+	token, hash, err := simplejwt.NewOpaqueToken("")
+	if err != nil {
+		return err
+	}
+
+	now := h.now()
+	err = h.storage.InsertEmailVerificationToken(ctx, u.ID, u.Email, hash, now, now.Add(emailVerificationTokenTTL))
+	if err != nil {
+		return err
+	}
+
+	return h.mailer.Send(ctx, mail.Message{
+		To:      u.Email,
+		Subject: "Verify your Conduit email",
+		Body: "Hi " + u.Username + ",\n\n" +
+			"Please confirm your email address by opening the link below.\n\n" +
+			h.opts.AppURL + "/verify-email?token=" + url.QueryEscape(token) + "\n",
+	})
+}
+
+type VerifyEmailRequestUser struct {
+	Token string `json:"token"`
+}
+
+type VerifyEmailRequest struct {
+	User VerifyEmailRequestUser `json:"user"`
+}
+
+func (h *handler) verifyEmail(w http.ResponseWriter, r *http.Request) {
+	var body VerifyEmailRequest
+	if err := ParseBody(r.Body, &body); err != nil {
+		InvalidJSON(w)
+		return
+	}
+
+	if body.User.Token == "" {
+		ValidationError(w, FieldErrMap{
+			"token": {"token is required"},
+		})
+		return
+	}
+
+	userID, err := h.storage.VerifyEmail(r.Context(), simplejwt.HashOpaqueToken(body.User.Token), h.now())
+	if err != nil {
+		switch {
+		case errors.Is(err, postgres.ErrNotFound):
+			ValidationError(w, FieldErrMap{
+				"token": {"token is invalid or expired"},
+			})
+			break
+		default:
+			logger(r.Context()).Error(err.Error())
+			InternalServerError(w)
+			break
+		}
+		return
+	}
+
+	h.users.Invalidate(userID)
+
+	u, err := h.storage.SelectUserByID(r.Context(), userID)
+	if err != nil {
+		logger(r.Context()).Error(err.Error())
+		InternalServerError(w)
+		return
+	}
+
+	JSON(w, map[string]any{
+		"user": u,
+	})
+}
+
+func (h *handler) resendVerification(w http.ResponseWriter, r *http.Request) {
+	u, ok := h.contextUser(w, r)
+	if !ok {
+		return
+	}
+	if u.EmailVerified() {
+		NewError("email is already verified", http.StatusBadRequest).Write(w)
+		return
+	}
+
+	sentAt, err := h.storage.LastEmailVerificationSentAt(r.Context(), u.ID)
+	if err != nil {
+		logger(r.Context()).Error(err.Error())
+		InternalServerError(w)
+		return
+	}
+
+	if sentAt.Valid {
+		if wait := sentAt.Time.Add(emailVerificationResend).Sub(h.now()); wait > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(int(wait.Seconds())+1))
+			NewError("verification email was sent recently", http.StatusTooManyRequests).Write(w)
+			return
+		}
+	}
+
+	if err := h.sendVerificationEmail(r.Context(), u); err != nil {
+		logger(r.Context()).Error("sending verification mail", slog.String("msg", err.Error()))
+		InternalServerError(w)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// requireVerified rejects users with an unverified email when the server is
+// configured to require verification.
+func (h *handler) requireVerified(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if h.opts.RequireVerifiedEmail {
+			u, ok := h.contextUser(w, r)
+			if !ok {
+				return
+			}
+
+			if !u.EmailVerified() {
+				NewError("email is not verified", http.StatusForbidden).Write(w)
+				return
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
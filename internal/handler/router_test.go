@@ -0,0 +1,68 @@
+package handler
+
+import (
+	"crypto/ed25519"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/askerdev/realworld-clone-go/internal/mem"
+	"github.com/askerdev/realworld-clone-go/pkg/simplejwt"
+	"github.com/jmoiron/sqlx"
+)
+
+func newBenchmarkHandler(b *testing.B) *handler {
+	_, key, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	keyset := simplejwt.NewKeyset()
+	if err := keyset.SetSigningKey(key); err != nil {
+		b.Fatal(err)
+	}
+
+	validator, err := simplejwt.NewValidator(keyset, mem.NewJWTCache(time.Minute), simplejwt.DefaultOptions())
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	// none of the benchmarked requests reach the database
+	return New(sqlx.NewDb(nil, "pgx"), nil, validator, nil, Options{
+		Logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+	})
+}
+
+// BenchmarkRouting compares serving from the router built in New with
+// building the routes for every request, as ServeHTTP used to.
+func BenchmarkRouting(b *testing.B) {
+	h := newBenchmarkHandler(b)
+
+	requests := map[string]func() *http.Request{
+		"public": func() *http.Request {
+			return httptest.NewRequest(http.MethodGet, "/livez", nil)
+		},
+		"auth": func() *http.Request {
+			return httptest.NewRequest(http.MethodGet, "/api/user", nil)
+		},
+	}
+
+	for name, newRequest := range requests {
+		b.Run(name+"/prebuilt", func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				h.ServeHTTP(httptest.NewRecorder(), newRequest())
+			}
+		})
+
+		b.Run(name+"/per-request", func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				h.routes().ServeHTTP(httptest.NewRecorder(), newRequest())
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package mail
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Outbox is a Mailer for local development and tests. It writes every
+// message as an .eml file into dir. When dir is empty it only logs the
+// recipient and subject: bodies carry login and reset tokens, which must not
+// end up in logs.
+type Outbox struct {
+	from string
+	dir  string
+}
+
+func NewOutbox(from, dir string) (*Outbox, error) {
+	if dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("unable to create outbox dir: %w", err)
+		}
+	}
+
+	return &Outbox{
+		from: from,
+		dir:  dir,
+	}, nil
+}
+
+func (o *Outbox) Send(_ context.Context, msg Message) error {
+	if o.dir == "" {
+		slog.Info(
+			"mail",
+			slog.String("to", msg.To),
+			slog.String("subject", msg.Subject),
+		)
+		return nil
+	}
+
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	name := fmt.Sprintf("%s-%s.eml", now.UTC().Format("20060102T150405.000000000"), hex.EncodeToString(suffix))
+
+	return os.WriteFile(filepath.Join(o.dir, name), render(o.from, msg, now), 0o644)
+}
@@ -0,0 +1,42 @@
+package mail
+
+import (
+	"context"
+	"net"
+	"net/smtp"
+	"time"
+)
+
+type SMTPConfig struct {
+	Addr     string
+	Username string
+	Password string
+	From     string
+}
+
+type SMTP struct {
+	cfg SMTPConfig
+}
+
+func NewSMTP(cfg SMTPConfig) *SMTP {
+	return &SMTP{cfg}
+}
+
+func (s *SMTP) Send(_ context.Context, msg Message) error {
+	var auth smtp.Auth
+	if s.cfg.Username != "" {
+		host, _, err := net.SplitHostPort(s.cfg.Addr)
+		if err != nil {
+			return err
+		}
+		auth = smtp.PlainAuth("", s.cfg.Username, s.cfg.Password, host)
+	}
+
+	return smtp.SendMail(
+		s.cfg.Addr,
+		auth,
+		s.cfg.From,
+		[]string{msg.To},
+		render(s.cfg.From, msg, time.Now()),
+	)
+}
@@ -1,26 +1,99 @@
 package mem
 
-import "time"
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type jwtCacheEntry struct {
+	value     time.Time
+	expiresAt time.Time
+}
 
 type JWTCache struct {
-	storage map[uint64]time.Time
+	mu      sync.RWMutex
+	ttl     time.Duration
+	storage map[uint64]jwtCacheEntry
+	revoked map[string]time.Time
 }
 
-func NewJWTCache() *JWTCache {
-	return &JWTCache{}
+func NewJWTCache(ttl time.Duration) *JWTCache {
+	return &JWTCache{
+		ttl:     ttl,
+		storage: map[uint64]jwtCacheEntry{},
+		revoked: map[string]time.Time{},
+	}
 }
 
-func (c *JWTCache) Get(key uint64) (time.Time, bool) {
-	time, ok := c.storage[key]
-	return time, ok
+func (c *JWTCache) Get(key uint64) (time.Time, bool, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	entry, ok := c.storage[key]
+	if !ok || time.Now().After(entry.expiresAt) {
+		return time.Time{}, false, nil
+	}
+
+	return entry.value, true, nil
 }
 
 func (c *JWTCache) Set(key uint64, value time.Time) error {
-	if c.storage == nil {
-		c.storage = map[uint64]time.Time{}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.storage[key] = jwtCacheEntry{
+		value:     value,
+		expiresAt: time.Now().Add(c.ttl),
 	}
 
-	c.storage[key] = value
+	return nil
+}
+
+func (c *JWTCache) Revoke(jti string, expiresAt time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.revoked[jti] = expiresAt
 
 	return nil
 }
+
+func (c *JWTCache) Revoked(jti string) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	_, ok := c.revoked[jti]
+	return ok
+}
+
+func (c *JWTCache) evict() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	for key, entry := range c.storage {
+		if now.After(entry.expiresAt) {
+			delete(c.storage, key)
+		}
+	}
+	for jti, expiresAt := range c.revoked {
+		if now.After(expiresAt) {
+			delete(c.revoked, jti)
+		}
+	}
+}
+
+func (c *JWTCache) RunEviction(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.evict()
+		}
+	}
+}
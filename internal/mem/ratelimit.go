@@ -0,0 +1,68 @@
+package mem
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/askerdev/realworld-clone-go/pkg/ratelimit"
+)
+
+type rateLimitEntry struct {
+	bucket ratelimit.Bucket
+	// fullAt is when the bucket has refilled; it can be dropped after.
+	fullAt time.Time
+}
+
+// RateLimitStore keeps buckets in memory, so every replica limits on its
+// own.
+type RateLimitStore struct {
+	mu      sync.Mutex
+	buckets map[string]rateLimitEntry
+}
+
+func NewRateLimitStore() *RateLimitStore {
+	return &RateLimitStore{
+		buckets: map[string]rateLimitEntry{},
+	}
+}
+
+func (s *RateLimitStore) Take(_ context.Context, key string, rate ratelimit.Rate) (ratelimit.Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	bucket, res := rate.Take(s.buckets[key].bucket, now)
+	s.buckets[key] = rateLimitEntry{
+		bucket: bucket,
+		fullAt: now.Add(res.Reset),
+	}
+
+	return res, nil
+}
+
+func (s *RateLimitStore) evict() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for key, entry := range s.buckets {
+		if now.After(entry.fullAt) {
+			delete(s.buckets, key)
+		}
+	}
+}
+
+func (s *RateLimitStore) RunEviction(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.evict()
+		}
+	}
+}
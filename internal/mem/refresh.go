@@ -0,0 +1,105 @@
+package mem
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/askerdev/realworld-clone-go/pkg/simplejwt"
+)
+
+type RefreshStore struct {
+	mu      sync.Mutex
+	storage map[string]simplejwt.RefreshToken
+}
+
+func NewRefreshStore() *RefreshStore {
+	return &RefreshStore{
+		storage: map[string]simplejwt.RefreshToken{},
+	}
+}
+
+func (s *RefreshStore) Save(hash string, token simplejwt.RefreshToken) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.storage[hash] = token
+
+	return nil
+}
+
+func (s *RefreshStore) Get(hash string) (simplejwt.RefreshToken, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	token, ok := s.storage[hash]
+	return token, ok, nil
+}
+
+func (s *RefreshStore) Use(hash string) (simplejwt.RefreshToken, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	token, ok := s.storage[hash]
+	if !ok {
+		return simplejwt.RefreshToken{}, false, nil
+	}
+
+	used := token
+	used.Used = true
+	s.storage[hash] = used
+
+	return token, true, nil
+}
+
+func (s *RefreshStore) RevokeFamily(familyID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for hash, token := range s.storage {
+		if token.FamilyID == familyID {
+			delete(s.storage, hash)
+		}
+	}
+
+	return nil
+}
+
+func (s *RefreshStore) RevokeUser(userID uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for hash, token := range s.storage {
+		if token.UserID == userID {
+			delete(s.storage, hash)
+		}
+	}
+
+	return nil
+}
+
+func (s *RefreshStore) evict() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for hash, token := range s.storage {
+		if now.After(token.ExpiresAt) {
+			delete(s.storage, hash)
+		}
+	}
+}
+
+func (s *RefreshStore) RunEviction(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.evict()
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package mem
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/askerdev/realworld-clone-go/internal/domain/entity"
+	"github.com/askerdev/realworld-clone-go/pkg/simplejwt"
+)
+
+type userCacheEntry struct {
+	user      *entity.User
+	expiresAt time.Time
+}
+
+// UserCache keeps recently loaded users for a short time so authenticated
+// requests don't hit the database for every call. Each replica has its own,
+// and Invalidate only clears the local copy, so the TTL is how long other
+// replicas may keep serving a user as it was before a change.
+type UserCache struct {
+	mu      sync.RWMutex
+	ttl     time.Duration
+	load    func(ctx context.Context, id uint64) (*entity.User, error)
+	storage map[uint64]userCacheEntry
+}
+
+func NewUserCache(
+	load func(ctx context.Context, id uint64) (*entity.User, error),
+	ttl time.Duration,
+) *UserCache {
+	return &UserCache{
+		ttl:     ttl,
+		load:    load,
+		storage: map[uint64]userCacheEntry{},
+	}
+}
+
+func (c *UserCache) LoadUser(ctx context.Context, id uint64) (*entity.User, error) {
+	c.mu.RLock()
+	entry, ok := c.storage[id]
+	c.mu.RUnlock()
+
+	if !ok || time.Now().After(entry.expiresAt) {
+		user, err := c.load(ctx, id)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return nil, simplejwt.ErrUserNotFound
+			}
+			return nil, err
+		}
+
+		entry = userCacheEntry{
+			user:      user,
+			expiresAt: time.Now().Add(c.ttl),
+		}
+
+		c.mu.Lock()
+		c.storage[id] = entry
+		c.mu.Unlock()
+	}
+
+	u := *entry.user
+	return &u, nil
+}
+
+func (c *UserCache) Invalidate(id uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.storage, id)
+}
+
+func (c *UserCache) evict() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	for id, entry := range c.storage {
+		if now.After(entry.expiresAt) {
+			delete(c.storage, id)
+		}
+	}
+}
+
+// RunEviction drops expired users, so the cache only holds those who
+// authenticated within the last TTL.
+func (c *UserCache) RunEviction(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.evict()
+		}
+	}
+}
@@ -0,0 +1,169 @@
+// Package metrics collects the Prometheus metrics served on the admin
+// listener. A nil *Metrics is valid and records nothing.
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/askerdev/realworld-clone-go/pkg/router"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "conduit"
+
+type Metrics struct {
+	registry *prometheus.Registry
+
+	httpRequests  *prometheus.CounterVec
+	httpDuration  *prometheus.HistogramVec
+	queryDuration *prometheus.HistogramVec
+
+	registrations   prometheus.Counter
+	articlesCreated prometheus.Counter
+	commentsCreated prometheus.Counter
+	favorites       prometheus.Counter
+	follows         prometheus.Counter
+}
+
+// New registers the service metrics, the Go runtime and process metrics
+// and the connection pool stats of db.
+func New(db *sql.DB) *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "HTTP requests by route pattern, method and status.",
+		}, []string{"route", "method", "status"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "HTTP request latency by route pattern, method and status.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"route", "method", "status"}),
+		queryDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "db_query_duration_seconds",
+			Help:      "Duration of storage methods, including their transactions.",
+			Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+		}, []string{"method"}),
+		registrations: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "registrations_total",
+			Help:      "Users registered.",
+		}),
+		articlesCreated: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "articles_created_total",
+			Help:      "Articles created.",
+		}),
+		commentsCreated: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "comments_created_total",
+			Help:      "Comments created.",
+		}),
+		favorites: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "favorites_total",
+			Help:      "Articles favorited.",
+		}),
+		follows: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "follows_total",
+			Help:      "Profiles followed.",
+		}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		collectors.NewDBStatsCollector(db, "conduit"),
+		m.httpRequests,
+		m.httpDuration,
+		m.queryDuration,
+		m.registrations,
+		m.articlesCreated,
+		m.commentsCreated,
+		m.favorites,
+		m.follows,
+	)
+
+	return m
+}
+
+// Handler serves the metrics in the Prometheus exposition format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// Middleware counts and times every request, labeled with the pattern of
+// the route that matched. It is meant as global router middleware;
+// requests no route matched are labeled "unmatched".
+func (m *Metrics) Middleware(next http.Handler) http.Handler {
+	if m == nil {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		sw := router.NewStatusWriter(w)
+		next.ServeHTTP(sw, r)
+
+		route := router.Pattern(r.Context())
+		if route == "" {
+			route = "unmatched"
+		}
+
+		labels := prometheus.Labels{
+			"route":  route,
+			"method": router.Method(r),
+			"status": strconv.Itoa(sw.Status()),
+		}
+		m.httpRequests.With(labels).Inc()
+		m.httpDuration.With(labels).Observe(time.Since(start).Seconds())
+	})
+}
+
+// ObserveQuery records how long a storage method took.
+func (m *Metrics) ObserveQuery(method string, d time.Duration) {
+	if m == nil {
+		return
+	}
+	m.queryDuration.WithLabelValues(method).Observe(d.Seconds())
+}
+
+func (m *Metrics) UserRegistered() {
+	if m != nil {
+		m.registrations.Inc()
+	}
+}
+
+func (m *Metrics) ArticleCreated() {
+	if m != nil {
+		m.articlesCreated.Inc()
+	}
+}
+
+func (m *Metrics) CommentCreated() {
+	if m != nil {
+		m.commentsCreated.Inc()
+	}
+}
+
+func (m *Metrics) ArticleFavorited() {
+	if m != nil {
+		m.favorites.Inc()
+	}
+}
+
+func (m *Metrics) ProfileFollowed() {
+	if m != nil {
+		m.follows.Inc()
+	}
+}
@@ -0,0 +1,401 @@
+// Package migrate applies the SQL migrations in migrations/ and records
+// them in the conduit_migrations table. Every operation holds a Postgres
+// advisory lock, so concurrent runs wait for each other.
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type Migration struct {
+	Version uint64
+	Name    string
+	Up      string
+	Down    string
+}
+
+type Status struct {
+	*Migration
+	// AppliedAt is nil for pending migrations.
+	AppliedAt *time.Time
+}
+
+type Migrator struct {
+	db         *sqlx.DB
+	migrations []*Migration
+}
+
+// Load reads <version>_<name>.up.sql and .down.sql pairs from fsys, sorted
+// by version.
+func Load(fsys fs.FS) ([]*Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[uint64]*Migration{}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".sql") {
+			continue
+		}
+
+		base, direction, ok := strings.Cut(strings.TrimSuffix(name, ".sql"), ".")
+		if !ok || (direction != "up" && direction != "down") {
+			return nil, fmt.Errorf("migration %s: want <version>_<name>.up.sql or .down.sql", name)
+		}
+
+		versionString, title, _ := strings.Cut(base, "_")
+		version, err := strconv.ParseUint(versionString, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("migration %s: invalid version: %w", name, err)
+		}
+
+		body, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: title}
+			byVersion[version] = m
+		} else if m.Name != title {
+			return nil, fmt.Errorf("migration %d has two names: %s and %s", version, m.Name, title)
+		}
+
+		if direction == "up" {
+			m.Up = string(body)
+		} else {
+			m.Down = string(body)
+		}
+	}
+
+	migrations := make([]*Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up script", m.Version, m.Name)
+		}
+		migrations = append(migrations, m)
+	}
+
+	slices.SortFunc(migrations, func(a, b *Migration) int {
+		switch {
+		case a.Version < b.Version:
+			return -1
+		case a.Version > b.Version:
+			return 1
+		default:
+			return 0
+		}
+	})
+
+	return migrations, nil
+}
+
+func New(db *sqlx.DB, fsys fs.FS) (*Migrator, error) {
+	migrations, err := Load(fsys)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{
+		db:         db,
+		migrations: migrations,
+	}, nil
+}
+
+// Latest is the version the embedded migrations bring the schema to.
+func (m *Migrator) Latest() uint64 {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+const createTableQuery = `
+  CREATE TABLE IF NOT EXISTS conduit_migrations (
+    version BIGINT PRIMARY KEY,
+    name TEXT NOT NULL,
+    applied_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+  )`
+
+// init creates the version table. A database that was migrated with the
+// migrate CLI has its schema_migrations version carried over, so nothing
+// is applied twice.
+func (m *Migrator) init(ctx context.Context) error {
+	if _, err := m.db.ExecContext(ctx, createTableQuery); err != nil {
+		return err
+	}
+
+	var count int
+	if err := m.db.GetContext(ctx, &count, `SELECT COUNT(*) FROM conduit_migrations`); err != nil {
+		return err
+	}
+
+	var legacy sql.NullString
+	if err := m.db.GetContext(ctx, &legacy, `SELECT to_regclass('schema_migrations')::TEXT`); err != nil {
+		return err
+	}
+
+	if count > 0 || !legacy.Valid {
+		return nil
+	}
+
+	var row struct {
+		Version uint64 `db:"version"`
+		Dirty   bool   `db:"dirty"`
+	}
+	err := m.db.GetContext(ctx, &row, `SELECT version, dirty FROM schema_migrations LIMIT 1`)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		return err
+	}
+
+	if row.Dirty {
+		return fmt.Errorf("schema_migrations is dirty at version %d, fix it by hand first", row.Version)
+	}
+
+	for _, migration := range m.migrations {
+		if migration.Version > row.Version {
+			break
+		}
+
+		_, err := m.db.ExecContext(
+			ctx,
+			`INSERT INTO conduit_migrations (version, name) VALUES ($1, $2)`,
+			migration.Version, migration.Name,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m *Migrator) applied(ctx context.Context) (map[uint64]time.Time, error) {
+	var rows []struct {
+		Version   uint64    `db:"version"`
+		AppliedAt time.Time `db:"applied_at"`
+	}
+	if err := m.db.SelectContext(ctx, &rows, `SELECT version, applied_at FROM conduit_migrations`); err != nil {
+		return nil, err
+	}
+
+	applied := make(map[uint64]time.Time, len(rows))
+	for _, row := range rows {
+		applied[row.Version] = row.AppliedAt
+	}
+
+	return applied, nil
+}
+
+// lockKey identifies the migration lock among the database's advisory
+// locks, so replicas starting together apply migrations one at a time.
+const lockKey int64 = 0x636f6e64756974
+
+// withLock runs fn while holding the migration lock. Advisory locks belong
+// to a session, so the lock is taken and released on one pinned connection.
+func (m *Migrator) withLock(ctx context.Context, fn func() error) error {
+	conn, err := m.db.Connx(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, lockKey); err != nil {
+		return fmt.Errorf("taking migration lock: %w", err)
+	}
+	defer conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, lockKey)
+
+	return fn()
+}
+
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	var statuses []Status
+	err := m.withLock(ctx, func() error {
+		var err error
+		statuses, err = m.status(ctx)
+		return err
+	})
+
+	return statuses, err
+}
+
+// Pending returns the migrations that haven't been applied yet.
+func (m *Migrator) Pending(ctx context.Context) ([]*Migration, error) {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	pending := []*Migration{}
+	for _, status := range statuses {
+		if status.AppliedAt == nil {
+			pending = append(pending, status.Migration)
+		}
+	}
+
+	return pending, nil
+}
+
+// Check reports whether every migration has been applied. Unlike Pending
+// it neither takes the lock nor creates the version table, so it is cheap
+// enough for readiness probes and never waits on a running migration.
+func (m *Migrator) Check(ctx context.Context) error {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return err
+	}
+
+	var pending []*Migration
+	for _, migration := range m.migrations {
+		if _, ok := applied[migration.Version]; !ok {
+			pending = append(pending, migration)
+		}
+	}
+
+	if len(pending) > 0 {
+		return fmt.Errorf(
+			"%d migrations pending, first %d_%s",
+			len(pending), pending[0].Version, pending[0].Name,
+		)
+	}
+
+	return nil
+}
+
+func (m *Migrator) status(ctx context.Context) ([]Status, error) {
+	if err := m.init(ctx); err != nil {
+		return nil, err
+	}
+
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		status := Status{Migration: migration}
+		if at, ok := applied[migration.Version]; ok {
+			status.AppliedAt = &at
+		}
+		statuses = append(statuses, status)
+	}
+
+	return statuses, nil
+}
+
+// Up applies every pending migration in version order and returns them.
+func (m *Migrator) Up(ctx context.Context) ([]*Migration, error) {
+	var done []*Migration
+	err := m.withLock(ctx, func() error {
+		var err error
+		done, err = m.up(ctx)
+		return err
+	})
+
+	return done, err
+}
+
+func (m *Migrator) up(ctx context.Context) ([]*Migration, error) {
+	statuses, err := m.status(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	done := []*Migration{}
+	for _, status := range statuses {
+		if status.AppliedAt != nil {
+			continue
+		}
+
+		err := m.run(ctx, status.Migration.Up,
+			`INSERT INTO conduit_migrations (version, name) VALUES ($1, $2)`,
+			status.Version, status.Name,
+		)
+		if err != nil {
+			return done, fmt.Errorf("applying %d_%s: %w", status.Version, status.Name, err)
+		}
+		done = append(done, status.Migration)
+	}
+
+	return done, nil
+}
+
+// Down reverts the last steps applied migrations, newest first.
+func (m *Migrator) Down(ctx context.Context, steps int) ([]*Migration, error) {
+	var done []*Migration
+	err := m.withLock(ctx, func() error {
+		var err error
+		done, err = m.down(ctx, steps)
+		return err
+	})
+
+	return done, err
+}
+
+func (m *Migrator) down(ctx context.Context, steps int) ([]*Migration, error) {
+	statuses, err := m.status(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	done := []*Migration{}
+	for i := len(statuses) - 1; i >= 0 && len(done) < steps; i-- {
+		status := statuses[i]
+		if status.AppliedAt == nil {
+			continue
+		}
+
+		if status.Migration.Down == "" {
+			return done, fmt.Errorf("migration %d_%s has no down script", status.Version, status.Name)
+		}
+
+		err := m.run(ctx, status.Migration.Down,
+			`DELETE FROM conduit_migrations WHERE version = $1`,
+			status.Version,
+		)
+		if err != nil {
+			return done, fmt.Errorf("reverting %d_%s: %w", status.Version, status.Name, err)
+		}
+		done = append(done, status.Migration)
+	}
+
+	return done, nil
+}
+
+// run executes a migration script and its bookkeeping in one transaction.
+func (m *Migrator) run(ctx context.Context, script, record string, args ...any) error {
+	tx, err := m.db.BeginTxx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, record, args...); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
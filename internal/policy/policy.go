@@ -0,0 +1,42 @@
+// Package policy decides what a user may do with a resource. Handlers ask
+// it before touching storage, so queries stay free of ownership rules.
+package policy
+
+import "github.com/askerdev/realworld-clone-go/internal/domain/entity"
+
+func owns(u *entity.User, author *entity.Profile) bool {
+	return u != nil && author != nil && u.ID == author.ID
+}
+
+func hasRole(u *entity.User, role entity.Role) bool {
+	return u != nil && u.Role.AtLeast(role)
+}
+
+// CanModerate lets u act on anyone's content, including that of suspended
+// users.
+func CanModerate(u *entity.User) bool {
+	return hasRole(u, entity.RoleModerator)
+}
+
+func CanUpdateArticle(u *entity.User, a *entity.Article) bool {
+	return owns(u, a.Author)
+}
+
+func CanDeleteArticle(u *entity.User, a *entity.Article) bool {
+	return owns(u, a.Author) || CanModerate(u)
+}
+
+func CanDeleteComment(u *entity.User, c *entity.Comment) bool {
+	return owns(u, c.Author) || CanModerate(u)
+}
+
+func CanManageUsers(u *entity.User) bool {
+	return hasRole(u, entity.RoleAdmin)
+}
+
+// CanManageUser guards actions an admin takes against another account.
+// Admins can't suspend, reset or delete themselves, so there is always a
+// way back in.
+func CanManageUser(u *entity.User, target *entity.User) bool {
+	return CanManageUsers(u) && target != nil && u.ID != target.ID
+}
@@ -0,0 +1,159 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/askerdev/realworld-clone-go/internal/domain/entity"
+	"github.com/guregu/null/v5"
+)
+
+type SelectUsersParams struct {
+	// Query matches a substring of the email or username.
+	Query  null.String
+	Limit  null.Int
+	Offset null.Int
+}
+
+type userRowWithTotal struct {
+	entity.User
+	Total uint `db:"total"`
+}
+
+func (s *Storage) SelectUsers(
+	ctx context.Context,
+	params *SelectUsersParams,
+) (_ []*entity.User, _ uint, err error) {
+	ctx, done := s.begin(ctx, "SelectUsers")
+	defer done(&err)
+
+	where := ""
+	end := ""
+	args := NewArgs()
+
+	if params.Query.Valid && params.Query.String != "" {
+		args.Append("%" + escapeLike(params.Query.String) + "%")
+		where = " WHERE email ILIKE " + args.Placeholder + " OR username ILIKE " + args.Placeholder
+	}
+
+	if params.Limit.Valid && params.Limit.Int64 > 0 && params.Limit.Int64 <= 100 {
+		end += " LIMIT " + strconv.FormatUint(uint64(params.Limit.Int64), 10)
+	} else {
+		end += " LIMIT 20"
+	}
+
+	if params.Offset.Valid && params.Offset.Int64 > 0 {
+		end += " OFFSET " + strconv.FormatUint(uint64(params.Offset.Int64), 10)
+	}
+
+	query := `
+    SELECT *, COUNT(*) OVER () AS total
+    FROM users` + where + `
+    ORDER BY created_at DESC, id DESC` + end
+
+	rows, err := s.db.QueryxContext(ctx, query, args.Values...)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var total uint
+	users := []*entity.User{}
+	for rows.Next() {
+		row := &userRowWithTotal{}
+		if err := rows.StructScan(row); err != nil {
+			rows.Close()
+			return nil, 0, err
+		}
+		total = row.Total
+		users = append(users, &row.User)
+	}
+
+	if err := rows.Close(); err != nil {
+		return nil, 0, err
+	}
+
+	return users, total, nil
+}
+
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+}
+
+// SetUserSuspended suspends or reinstates a user. Suspending an already
+// suspended user keeps the original timestamp.
+func (s *Storage) SetUserSuspended(
+	ctx context.Context,
+	id uint64,
+	suspended bool,
+) (_ *entity.User, err error) {
+	ctx, done := s.begin(ctx, "SetUserSuspended")
+	defer done(&err)
+
+	const query = `
+    UPDATE users
+    SET suspended_at = CASE WHEN $2 THEN COALESCE(suspended_at, NOW()) END
+    WHERE id = $1
+    RETURNING *`
+
+	u := &entity.User{}
+	if err := s.db.QueryRowxContext(ctx, query, id, suspended).StructScan(u); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return u, nil
+}
+
+// DeleteUser removes a user along with everything they authored, and takes
+// their favorites and follows out of other users' counts.
+func (s *Storage) DeleteUser(ctx context.Context, id uint64) (err error) {
+	ctx, done := s.begin(ctx, "DeleteUser")
+	defer done(&err)
+
+	queries := []string{
+		`UPDATE articles SET favorites_count = favorites_count - 1
+      WHERE id IN (SELECT article_id FROM favorites_articles_rel WHERE user_id = $1)`,
+		`DELETE FROM favorites_articles_rel WHERE user_id = $1`,
+		`DELETE FROM subscriptions WHERE user_id = $1 OR profile_id = $1`,
+		`DELETE FROM comments
+      WHERE author_id = $1 OR article_id IN (SELECT id FROM articles WHERE author_id = $1)`,
+		`DELETE FROM articles WHERE author_id = $1`,
+	}
+	const deleteUserQuery = `DELETE FROM users WHERE id = $1`
+
+	tx, err := s.db.BeginTxx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return err
+	}
+
+	for _, query := range queries {
+		if _, err := tx.ExecContext(ctx, query, id); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	res, err := tx.ExecContext(ctx, deleteUserQuery, id)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if affected == 0 {
+		tx.Rollback()
+		return ErrNotFound
+	}
+
+	return tx.Commit()
+}
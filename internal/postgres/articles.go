@@ -23,7 +23,10 @@ type CreateArticleParams struct {
 func (s *Storage) CreateArticle(
 	ctx context.Context,
 	params *CreateArticleParams,
-) (*entity.Article, error) {
+) (_ *entity.Article, err error) {
+	ctx, done := s.begin(ctx, "CreateArticle")
+	defer done(&err)
+
 	tx, err := s.db.BeginTxx(ctx, &sql.TxOptions{})
 	if err != nil {
 		return nil, err
@@ -82,17 +85,20 @@ func (s *Storage) CreateArticle(
 }
 
 type UpdateArticleParams struct {
-	OriginalSlug string
-	Slug         null.String
-	Title        null.String
-	Description  null.String
-	Body         null.String
+	ID          uint64
+	Slug        null.String
+	Title       null.String
+	Description null.String
+	Body        null.String
 }
 
 func (s *Storage) UpdateArticle(
 	ctx context.Context,
 	params *UpdateArticleParams,
-) error {
+) (err error) {
+	ctx, done := s.begin(ctx, "UpdateArticle")
+	defer done(&err)
+
 	fields := []string{}
 	args := NewArgs()
 
@@ -120,11 +126,11 @@ func (s *Storage) UpdateArticle(
 	args.Append(time.Now())
 	fields = append(fields, "updated_at = "+args.Placeholder)
 
-	args.Append(params.OriginalSlug)
+	args.Append(params.ID)
 
 	updateArticleQuery := `
     UPDATE articles SET ` + strings.Join(fields, ", ") +
-		` WHERE slug = ` + args.Placeholder
+		` WHERE id = ` + args.Placeholder
 
 	res, err := s.db.ExecContext(
 		ctx,
@@ -145,15 +151,17 @@ func (s *Storage) UpdateArticle(
 
 func (s *Storage) RemoveArticle(
 	ctx context.Context,
-	slug string,
-	authorID uint64,
-) error {
-	const query = `DELETE FROM articles CASCADE WHERE slug = $1 AND author_id = $2`
+	articleID uint64,
+) (err error) {
+	ctx, done := s.begin(ctx, "RemoveArticle")
+	defer done(&err)
+
+	const query = `DELETE FROM articles WHERE id = $1`
 
 	res, err := s.db.ExecContext(
 		ctx,
 		query,
-		slug, authorID,
+		articleID,
 	)
 	if err != nil {
 		return err
@@ -180,12 +188,18 @@ type SelectArticlesParams struct {
 	Slug                null.String
 	Limit               null.Int
 	Offset              null.Int
+	// IncludeSuspended also finds articles of suspended users, for
+	// moderation.
+	IncludeSuspended bool
 }
 
 func (s *Storage) SelectArticles(
 	ctx context.Context,
 	params *SelectArticlesParams,
-) ([]*entity.Article, uint, error) {
+) (_ []*entity.Article, _ uint, err error) {
+	ctx, done := s.begin(ctx, "SelectArticles")
+	defer done(&err)
+
 	conditionalJoin := ""
 	authenticatedJoin := ""
 	end := ""
@@ -239,10 +253,11 @@ func (s *Storage) SelectArticles(
 		end += " OFFSET " + strconv.FormatUint(uint64(params.Offset.Int64), 10)
 	}
 
-	whereStart := " "
-	if len(where) > 0 {
-		whereStart = " WHERE "
+	// suspended users' articles are hidden until they are reinstated
+	if !params.IncludeSuspended {
+		where = append(where, "u.suspended_at IS NULL")
 	}
+	whereStart := " WHERE "
 
 	authenticatedSelect := " "
 	if len(authenticatedJoin) > 0 {
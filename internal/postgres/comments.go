@@ -11,12 +11,18 @@ type SelectCommentsParams struct {
 	CommentID   *uint64
 	ArticleSlug string
 	UserID      *uint64
+	// IncludeSuspended also finds comments of suspended users, for
+	// moderation.
+	IncludeSuspended bool
 }
 
 func (s *Storage) SelectComments(
 	ctx context.Context,
 	params *SelectCommentsParams,
-) ([]*entity.Comment, error) {
+) (_ []*entity.Comment, err error) {
+	ctx, done := s.begin(ctx, "SelectComments")
+	defer done(&err)
+
 	conditionalSelect := ""
 	conditionalJoin := ""
 	conditionalWhere := []string{}
@@ -38,6 +44,11 @@ func (s *Storage) SelectComments(
 	conditionalWhere = append(conditionalWhere, `c.article_id IN (
       SELECT id AS aid FROM articles WHERE slug = `+args.Placeholder+`)`)
 
+	// suspended users' comments are hidden until they are reinstated
+	if !params.IncludeSuspended {
+		conditionalWhere = append(conditionalWhere, "u.suspended_at IS NULL")
+	}
+
 	if len(conditionalWhere) > 0 {
 		where = " WHERE "
 	}
@@ -88,7 +99,10 @@ type InsertCommentParams struct {
 func (s *Storage) InsertComment(
 	ctx context.Context,
 	params *InsertCommentParams,
-) (*entity.Comment, error) {
+) (_ *entity.Comment, err error) {
+	ctx, done := s.begin(ctx, "InsertComment")
+	defer done(&err)
+
 	const query = `
     INSERT INTO comments
       (body, author_id, article_id)
@@ -116,28 +130,34 @@ func (s *Storage) InsertComment(
 	}
 
 	if len(comments) < 1 {
-		panic("inserted comment not found")
+		return nil, ErrInsertedCommentNotFound
 	}
 
 	return comments[0], nil
 }
 
-type DeleteCommentParams struct {
-	CommentID   uint64
-	ArticleSlug string
-	UserID      uint64
-}
-
 func (s *Storage) DeleteComment(
 	ctx context.Context,
-	params *DeleteCommentParams,
-) error {
-	const query = `
-    DELETE FROM comments
-    WHERE id = $1 AND author_id = $2 AND article_id IN (
-      SELECT id FROM articles WHERE slug = $3 LIMIT 1
-    )`
+	commentID uint64,
+) (err error) {
+	ctx, done := s.begin(ctx, "DeleteComment")
+	defer done(&err)
+
+	const query = `DELETE FROM comments WHERE id = $1`
+
+	res, err := s.db.ExecContext(ctx, query, commentID)
+	if err != nil {
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return ErrNotFound
+	}
 
-	_, err := s.db.ExecContext(ctx, query, params.CommentID, params.UserID, params.ArticleSlug)
-	return err
+	return nil
 }
@@ -1,6 +1,8 @@
 package postgres
 
 import (
+	"strings"
+
 	"github.com/askerdev/realworld-clone-go/internal/domain/entity"
 )
 
@@ -45,3 +47,14 @@ func convertCommentRowToComment(commentRow *CommentRow) *entity.Comment {
 		UpdatedAt: commentRow.UpdatedAt,
 	}
 }
+
+func convertPersonalAccessTokenRowToDomain(row *PersonalAccessTokenRow) *entity.PersonalAccessToken {
+	return &entity.PersonalAccessToken{
+		ID:         row.ID,
+		Name:       row.Name,
+		Scopes:     strings.Fields(row.Scopes),
+		ExpiresAt:  row.ExpiresAt,
+		LastUsedAt: row.LastUsedAt,
+		CreatedAt:  row.CreatedAt,
+	}
+}
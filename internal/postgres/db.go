@@ -1,13 +1,85 @@
 package postgres
 
 import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/askerdev/realworld-clone-go/internal/tracing"
 	"github.com/jmoiron/sqlx"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// QueryObserver is told how long each Storage method took.
+type QueryObserver interface {
+	ObserveQuery(method string, d time.Duration)
+}
+
 type Storage struct {
-	db *sqlx.DB
+	db       *sqlx.DB
+	observer QueryObserver
+	tracer   trace.Tracer
 }
 
 func NewStorage(db *sqlx.DB) *Storage {
-	return &Storage{db}
+	return &Storage{
+		db:     db,
+		tracer: tracing.Tracer(),
+	}
+}
+
+// Observe reports the duration of every exported method to o.
+func (s *Storage) Observe(o QueryObserver) {
+	s.observer = o
+}
+
+func (s *Storage) Ping(ctx context.Context) (err error) {
+	ctx, done := s.begin(ctx, "Ping")
+	defer done(&err)
+
+	return s.db.PingContext(ctx)
+}
+
+// begin opens a span for a Storage method, so its statements are grouped
+// under it, and times it. Every exported method names its error result and
+// starts with:
+//
+//	ctx, done := s.begin(ctx, "InsertUser")
+//	defer done(&err)
+//
+// so done can mark the span failed.
+func (s *Storage) begin(ctx context.Context, method string) (context.Context, func(*error)) {
+	start := time.Now()
+	ctx, span := s.tracer.Start(ctx, "Storage."+method)
+
+	return ctx, func(errp *error) {
+		if err := *errp; err != nil && !isOutcome(err) {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+		if s.observer != nil {
+			s.observer.ObserveQuery(method, time.Since(start))
+		}
+	}
+}
+
+// isOutcome reports whether err answers the caller, such as a lookup that
+// found nothing or a conflict, rather than tells of a failure.
+func isOutcome(err error) bool {
+	for _, target := range []error{
+		sql.ErrNoRows,
+		ErrNotFound,
+		ErrUniqueConstraint,
+		ErrSubscriptionAlreadyExists,
+		ErrNoUpdateFields,
+	} {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+
+	return false
 }
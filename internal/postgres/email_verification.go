@@ -0,0 +1,111 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/guregu/null/v5"
+)
+
+func (s *Storage) InsertEmailVerificationToken(
+	ctx context.Context,
+	userID uint64,
+	email string,
+	hash string,
+	createdAt time.Time,
+	expiresAt time.Time,
+) (err error) {
+	ctx, done := s.begin(ctx, "InsertEmailVerificationToken")
+	defer done(&err)
+
+	const query = `
+    INSERT INTO email_verification_tokens
+      (hash, user_id, email, created_at, expires_at)
+    VALUES
+      ($1, $2, $3, $4, $5)`
+
+	_, err = s.db.ExecContext(ctx, query, hash, userID, email, createdAt, expiresAt)
+	return err
+}
+
+func (s *Storage) LastEmailVerificationSentAt(
+	ctx context.Context,
+	userID uint64,
+) (_ null.Time, err error) {
+	ctx, done := s.begin(ctx, "LastEmailVerificationSentAt")
+	defer done(&err)
+
+	const query = `SELECT MAX(created_at) FROM email_verification_tokens WHERE user_id = $1`
+
+	var sentAt null.Time
+	if err := s.db.QueryRowxContext(ctx, query, userID).Scan(&sentAt); err != nil {
+		return null.Time{}, err
+	}
+
+	return sentAt, nil
+}
+
+// VerifyEmail consumes a verification token still valid at now and marks
+// the address it was sent to as verified, as long as it is still the user's
+// current email.
+func (s *Storage) VerifyEmail(ctx context.Context, hash string, now time.Time) (_ uint64, err error) {
+	ctx, done := s.begin(ctx, "VerifyEmail")
+	defer done(&err)
+
+	tx, err := s.db.BeginTxx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return 0, err
+	}
+
+	const consumeQuery = `
+    DELETE FROM email_verification_tokens
+    WHERE hash = $1 AND expires_at > $2
+    RETURNING user_id, email`
+
+	var userID uint64
+	var email string
+	if err := tx.QueryRowxContext(ctx, consumeQuery, hash, now).Scan(&userID, &email); err != nil {
+		tx.Rollback()
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, ErrNotFound
+		}
+		return 0, err
+	}
+
+	const verifyQuery = `
+    UPDATE users SET email_verified_at = COALESCE(email_verified_at, NOW())
+    WHERE id = $1 AND email = $2`
+
+	res, err := tx.ExecContext(ctx, verifyQuery, userID, email)
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	if affected == 0 {
+		tx.Rollback()
+		return 0, ErrNotFound
+	}
+
+	const deleteTokensQuery = `DELETE FROM email_verification_tokens WHERE user_id = $1`
+
+	_, err = tx.ExecContext(ctx, deleteTokensQuery, userID)
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	return userID, nil
+}
@@ -7,4 +7,11 @@ var (
 	ErrSubscriptionAlreadyExists = errors.New("subscription already exists")
 	ErrNoUpdateFields            = errors.New("no update fields")
 	ErrNotFound                  = errors.New("resource not found")
+	ErrInsertedCommentNotFound   = errors.New("inserted comment not found")
 )
+
+// isUniqueViolation reports whether err is Postgres' unique_violation.
+func isUniqueViolation(err error) bool {
+	var state interface{ SQLState() string }
+	return errors.As(err, &state) && state.SQLState() == "23505"
+}
@@ -5,7 +5,10 @@ import (
 	"database/sql"
 )
 
-func (s *Storage) FavoriteArticle(ctx context.Context, userID uint64, articleID uint64) error {
+func (s *Storage) FavoriteArticle(ctx context.Context, userID uint64, articleID uint64) (err error) {
+	ctx, done := s.begin(ctx, "FavoriteArticle")
+	defer done(&err)
+
 	tx, err := s.db.BeginTxx(ctx, &sql.TxOptions{})
 	if err != nil {
 		return err
@@ -38,7 +41,10 @@ func (s *Storage) FavoriteArticle(ctx context.Context, userID uint64, articleID
 	return nil
 }
 
-func (s *Storage) UnfavoriteArticle(ctx context.Context, userID uint64, articleID uint64) error {
+func (s *Storage) UnfavoriteArticle(ctx context.Context, userID uint64, articleID uint64) (err error) {
+	ctx, done := s.begin(ctx, "UnfavoriteArticle")
+	defer done(&err)
+
 	tx, err := s.db.BeginTxx(ctx, &sql.TxOptions{})
 	if err != nil {
 		return err
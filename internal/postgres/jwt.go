@@ -0,0 +1,180 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/askerdev/realworld-clone-go/pkg/simplejwt"
+	"github.com/jmoiron/sqlx"
+)
+
+type JWTCache struct {
+	db  *sqlx.DB
+	ttl time.Duration
+}
+
+func NewJWTCache(db *sqlx.DB, ttl time.Duration) *JWTCache {
+	return &JWTCache{
+		db:  db,
+		ttl: ttl,
+	}
+}
+
+func (c *JWTCache) Get(key uint64) (time.Time, bool, error) {
+	const query = `SELECT issued_at FROM jwt_sessions WHERE user_id = $1 AND expires_at > NOW()`
+
+	var issuedAt time.Time
+	err := c.db.QueryRowxContext(context.Background(), query, key).Scan(&issuedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return time.Time{}, false, nil
+		}
+		return time.Time{}, false, err
+	}
+
+	return issuedAt, true, nil
+}
+
+func (c *JWTCache) Set(key uint64, value time.Time) error {
+	const query = `
+    INSERT INTO jwt_sessions
+      (user_id, issued_at, expires_at)
+    VALUES
+      ($1, $2, $3)
+    ON CONFLICT (user_id) DO UPDATE
+    SET issued_at = EXCLUDED.issued_at, expires_at = EXCLUDED.expires_at`
+
+	_, err := c.db.ExecContext(context.Background(), query, key, value, time.Now().Add(c.ttl))
+	return err
+}
+
+func (c *JWTCache) Revoke(jti string, expiresAt time.Time) error {
+	const query = `
+    INSERT INTO revoked_tokens
+      (jti, expires_at)
+    VALUES
+      ($1, $2)
+    ON CONFLICT (jti) DO NOTHING`
+
+	_, err := c.db.ExecContext(context.Background(), query, jti, expiresAt)
+	return err
+}
+
+func (c *JWTCache) Revoked(jti string) bool {
+	const query = `SELECT EXISTS (SELECT 1 FROM revoked_tokens WHERE jti = $1)`
+
+	var revoked bool
+	err := c.db.QueryRowxContext(context.Background(), query, jti).Scan(&revoked)
+	if err != nil {
+		slog.Error("selecting revoked token", slog.String("msg", err.Error()))
+		return true
+	}
+
+	return revoked
+}
+
+func (c *JWTCache) RunEviction(ctx context.Context, interval time.Duration) {
+	runEviction(
+		ctx, c.db, interval,
+		`DELETE FROM jwt_sessions WHERE expires_at < NOW()`,
+		`DELETE FROM revoked_tokens WHERE expires_at < NOW()`,
+	)
+}
+
+type RefreshStore struct {
+	db *sqlx.DB
+}
+
+func NewRefreshStore(db *sqlx.DB) *RefreshStore {
+	return &RefreshStore{db}
+}
+
+func (s *RefreshStore) Save(hash string, token simplejwt.RefreshToken) error {
+	const query = `
+    INSERT INTO refresh_tokens
+      (hash, family_id, user_id, used, expires_at)
+    VALUES
+      ($1, $2, $3, $4, $5)`
+
+	_, err := s.db.ExecContext(
+		context.Background(),
+		query,
+		hash, token.FamilyID, token.UserID, token.Used, token.ExpiresAt,
+	)
+	return err
+}
+
+func (s *RefreshStore) Get(hash string) (simplejwt.RefreshToken, bool, error) {
+	const query = `SELECT family_id, user_id, expires_at, used FROM refresh_tokens WHERE hash = $1`
+
+	token := simplejwt.RefreshToken{}
+	err := s.db.QueryRowxContext(context.Background(), query, hash).
+		Scan(&token.FamilyID, &token.UserID, &token.ExpiresAt, &token.Used)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return simplejwt.RefreshToken{}, false, nil
+		}
+		return simplejwt.RefreshToken{}, false, err
+	}
+
+	return token, true, nil
+}
+
+func (s *RefreshStore) Use(hash string) (simplejwt.RefreshToken, bool, error) {
+	const query = `
+    UPDATE refresh_tokens r SET used = TRUE
+    FROM (SELECT hash, used FROM refresh_tokens WHERE hash = $1 FOR UPDATE) prev
+    WHERE r.hash = prev.hash
+    RETURNING r.family_id, r.user_id, r.expires_at, prev.used`
+
+	token := simplejwt.RefreshToken{}
+	err := s.db.QueryRowxContext(context.Background(), query, hash).
+		Scan(&token.FamilyID, &token.UserID, &token.ExpiresAt, &token.Used)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return simplejwt.RefreshToken{}, false, nil
+		}
+		return simplejwt.RefreshToken{}, false, err
+	}
+
+	return token, true, nil
+}
+
+func (s *RefreshStore) RevokeFamily(familyID string) error {
+	const query = `DELETE FROM refresh_tokens WHERE family_id = $1`
+
+	_, err := s.db.ExecContext(context.Background(), query, familyID)
+	return err
+}
+
+func (s *RefreshStore) RevokeUser(userID uint64) error {
+	const query = `DELETE FROM refresh_tokens WHERE user_id = $1`
+
+	_, err := s.db.ExecContext(context.Background(), query, userID)
+	return err
+}
+
+func (s *RefreshStore) RunEviction(ctx context.Context, interval time.Duration) {
+	runEviction(ctx, s.db, interval, `DELETE FROM refresh_tokens WHERE expires_at < NOW()`)
+}
+
+func runEviction(ctx context.Context, db *sqlx.DB, interval time.Duration, queries ...string) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for _, query := range queries {
+				if _, err := db.ExecContext(ctx, query); err != nil && ctx.Err() == nil {
+					slog.Error("evicting expired rows", slog.String("msg", err.Error()))
+				}
+			}
+		}
+	}
+}
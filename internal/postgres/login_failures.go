@@ -0,0 +1,206 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/guregu/null/v5"
+)
+
+// LoginLockedUntil returns the latest lockout of keys that is still active
+// at now.
+func (s *Storage) LoginLockedUntil(ctx context.Context, now time.Time, keys ...string) (_ null.Time, err error) {
+	ctx, done := s.begin(ctx, "LoginLockedUntil")
+	defer done(&err)
+
+	if len(keys) == 0 {
+		return null.Time{}, nil
+	}
+
+	args := NewArgs(now)
+	placeholders := []string{}
+	for _, key := range keys {
+		args.Append(key)
+		placeholders = append(placeholders, args.Placeholder)
+	}
+
+	query := `
+    SELECT MAX(locked_until) FROM login_failures
+    WHERE locked_until > $1 AND key IN (` + strings.Join(placeholders, ", ") + `)`
+
+	var lockedUntil null.Time
+	if err := s.db.QueryRowxContext(ctx, query, args.Values...).Scan(&lockedUntil); err != nil {
+		return null.Time{}, err
+	}
+
+	return lockedUntil, nil
+}
+
+// IncrementLoginFailures bumps the failure counter of key and returns the
+// new value. Counters whose last failure is older than window start over.
+func (s *Storage) IncrementLoginFailures(
+	ctx context.Context,
+	key string,
+	window time.Duration,
+	now time.Time,
+) (_ int, err error) {
+	ctx, done := s.begin(ctx, "IncrementLoginFailures")
+	defer done(&err)
+
+	const query = `
+    INSERT INTO login_failures
+      (key, failures, last_failure_at)
+    VALUES
+      ($1, 1, $3)
+    ON CONFLICT (key) DO UPDATE
+    SET failures = CASE
+        WHEN login_failures.last_failure_at < $3 - make_interval(secs => $2) THEN 1
+        ELSE login_failures.failures + 1
+      END,
+      last_failure_at = $3
+    RETURNING failures`
+
+	var failures int
+	if err := s.db.QueryRowxContext(ctx, query, key, window.Seconds(), now).Scan(&failures); err != nil {
+		return 0, err
+	}
+
+	return failures, nil
+}
+
+type LockLoginParams struct {
+	Key         string
+	UserID      *uint64
+	IP          string
+	Failures    int
+	LockedUntil time.Time
+}
+
+func (s *Storage) LockLogin(ctx context.Context, params *LockLoginParams) (err error) {
+	ctx, done := s.begin(ctx, "LockLogin")
+	defer done(&err)
+
+	tx, err := s.db.BeginTxx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return err
+	}
+
+	const lockQuery = `UPDATE login_failures SET locked_until = $2 WHERE key = $1`
+
+	_, err = tx.ExecContext(ctx, lockQuery, params.Key, params.LockedUntil)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	const eventQuery = `
+    INSERT INTO lockout_events
+      (key, user_id, ip, failures, locked_until)
+    VALUES
+      ($1, $2, $3, $4, $5)`
+
+	_, err = tx.ExecContext(
+		ctx,
+		eventQuery,
+		params.Key, params.UserID, params.IP, params.Failures, params.LockedUntil,
+	)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *Storage) ClearLoginFailures(ctx context.Context, key string) (err error) {
+	ctx, done := s.begin(ctx, "ClearLoginFailures")
+	defer done(&err)
+
+	const query = `DELETE FROM login_failures WHERE key = $1`
+
+	_, err = s.db.ExecContext(ctx, query, key)
+	return err
+}
+
+// UnlockLogin lifts a lockout of key still active at now and marks its
+// events as unlocked. It returns ErrNotFound when key isn't locked.
+func (s *Storage) UnlockLogin(ctx context.Context, key string, now time.Time) (err error) {
+	ctx, done := s.begin(ctx, "UnlockLogin")
+	defer done(&err)
+
+	tx, err := s.db.BeginTxx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return err
+	}
+
+	const unlockQuery = `DELETE FROM login_failures WHERE key = $1 AND locked_until > $2`
+
+	res, err := tx.ExecContext(ctx, unlockQuery, key, now)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if affected == 0 {
+		tx.Rollback()
+		return ErrNotFound
+	}
+
+	const eventsQuery = `
+    UPDATE lockout_events SET unlocked_at = $2
+    WHERE key = $1 AND unlocked_at IS NULL AND locked_until > $2`
+
+	_, err = tx.ExecContext(ctx, eventsQuery, key, now)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// EvictLoginFailures drops counters whose last failure is older than window
+// at now and that aren't locked, as they would start over anyway.
+func (s *Storage) EvictLoginFailures(ctx context.Context, window time.Duration, now time.Time) (err error) {
+	ctx, done := s.begin(ctx, "EvictLoginFailures")
+	defer done(&err)
+
+	const query = `
+    DELETE FROM login_failures
+    WHERE last_failure_at < $2 - make_interval(secs => $1)
+      AND (locked_until IS NULL OR locked_until < $2)`
+
+	_, err = s.db.ExecContext(ctx, query, window.Seconds(), now)
+	return err
+}
+
+// RunLoginFailureEviction runs EvictLoginFailures every interval, on the
+// clock the lockouts are checked against.
+func (s *Storage) RunLoginFailureEviction(
+	ctx context.Context,
+	interval, window time.Duration,
+	now func() time.Time,
+) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.EvictLoginFailures(ctx, window, now()); err != nil && ctx.Err() == nil {
+				slog.Error("evicting expired rows", slog.String("msg", err.Error()))
+			}
+		}
+	}
+}
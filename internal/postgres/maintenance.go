@@ -0,0 +1,77 @@
+package postgres
+
+import (
+	"context"
+)
+
+// RecountFavorites fixes articles whose favorites_count drifted from the
+// favorites table and returns how many were off.
+func (s *Storage) RecountFavorites(ctx context.Context) (_ int64, err error) {
+	ctx, done := s.begin(ctx, "RecountFavorites")
+	defer done(&err)
+
+	const query = `
+    UPDATE articles a
+    SET favorites_count = counts.favorites
+    FROM (
+      SELECT a.id, COUNT(far.user_id) AS favorites
+      FROM articles a
+      LEFT JOIN favorites_articles_rel far ON far.article_id = a.id
+      GROUP BY a.id
+    ) counts
+    WHERE a.id = counts.id AND a.favorites_count <> counts.favorites`
+
+	res, err := s.db.ExecContext(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
+
+// PruneTags deletes tags no article uses anymore, so they drop out of the
+// tag list, and returns how many were removed.
+func (s *Storage) PruneTags(ctx context.Context) (_ int64, err error) {
+	ctx, done := s.begin(ctx, "PruneTags")
+	defer done(&err)
+
+	const query = `
+    DELETE FROM tags t
+    WHERE NOT EXISTS (SELECT 1 FROM tags_articles_rel tar WHERE tar.tag_id = t.id)`
+
+	res, err := s.db.ExecContext(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
+
+var reindexedTables = []string{
+	"users",
+	"subscriptions",
+	"articles",
+	"tags",
+	"tags_articles_rel",
+	"favorites_articles_rel",
+	"comments",
+}
+
+// Reindex rebuilds the indexes of the content tables and refreshes their
+// planner statistics.
+func (s *Storage) Reindex(ctx context.Context) (err error) {
+	ctx, done := s.begin(ctx, "Reindex")
+	defer done(&err)
+
+	for _, table := range reindexedTables {
+		if _, err := s.db.ExecContext(ctx, "REINDEX TABLE "+table); err != nil {
+			return err
+		}
+
+		if _, err := s.db.ExecContext(ctx, "ANALYZE "+table); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
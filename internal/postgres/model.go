@@ -50,3 +50,22 @@ type CommentRow struct {
 	CreatedAt    time.Time   `db:"created_at"`
 	UpdatedAt    time.Time   `db:"updated_at"`
 }
+
+type PersonalAccessTokenRow struct {
+	ID         uint64    `db:"id"`
+	UserID     uint64    `db:"user_id"`
+	Name       string    `db:"name"`
+	TokenHash  string    `db:"token_hash"`
+	Scopes     string    `db:"scopes"`
+	ExpiresAt  null.Time `db:"expires_at"`
+	LastUsedAt null.Time `db:"last_used_at"`
+	CreatedAt  time.Time `db:"created_at"`
+}
+
+type TOTPRow struct {
+	UserID       uint64    `db:"user_id"`
+	Secret       string    `db:"secret"`
+	EnabledAt    null.Time `db:"enabled_at"`
+	LastUsedStep int64     `db:"last_used_step"`
+	CreatedAt    time.Time `db:"created_at"`
+}
@@ -0,0 +1,81 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+func (s *Storage) InsertPasswordResetToken(
+	ctx context.Context,
+	userID uint64,
+	hash string,
+	expiresAt time.Time,
+) (err error) {
+	ctx, done := s.begin(ctx, "InsertPasswordResetToken")
+	defer done(&err)
+
+	const query = `
+    INSERT INTO password_reset_tokens
+      (hash, user_id, expires_at)
+    VALUES
+      ($1, $2, $3)`
+
+	_, err = s.db.ExecContext(ctx, query, hash, userID, expiresAt)
+	return err
+}
+
+// ResetPassword consumes a single-use reset token and sets the new password
+// hash of its user. It returns ErrNotFound for unknown, used or expired
+// tokens, expiry being checked at now.
+func (s *Storage) ResetPassword(
+	ctx context.Context,
+	hash string,
+	passwordHash string,
+	now time.Time,
+) (_ uint64, err error) {
+	ctx, done := s.begin(ctx, "ResetPassword")
+	defer done(&err)
+
+	tx, err := s.db.BeginTxx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return 0, err
+	}
+
+	const consumeQuery = `
+    UPDATE password_reset_tokens SET used_at = $2
+    WHERE hash = $1 AND used_at IS NULL AND expires_at > $2
+    RETURNING user_id`
+
+	var userID uint64
+	if err := tx.QueryRowxContext(ctx, consumeQuery, hash, now).Scan(&userID); err != nil {
+		tx.Rollback()
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, ErrNotFound
+		}
+		return 0, err
+	}
+
+	const updatePasswordQuery = `UPDATE users SET password = $1 WHERE id = $2`
+
+	_, err = tx.ExecContext(ctx, updatePasswordQuery, passwordHash, userID)
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	const deleteTokensQuery = `DELETE FROM password_reset_tokens WHERE user_id = $1 AND hash <> $2`
+
+	_, err = tx.ExecContext(ctx, deleteTokensQuery, userID, hash)
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	return userID, nil
+}
@@ -0,0 +1,76 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/askerdev/realworld-clone-go/pkg/ratelimit"
+	"github.com/jmoiron/sqlx"
+)
+
+// RateLimitStore keeps buckets in Postgres, so replicas share them. Time
+// comes from the database clock, which all replicas agree on.
+type RateLimitStore struct {
+	db *sqlx.DB
+}
+
+func NewRateLimitStore(db *sqlx.DB) *RateLimitStore {
+	return &RateLimitStore{db}
+}
+
+func (s *RateLimitStore) Take(ctx context.Context, key string, rate ratelimit.Rate) (ratelimit.Result, error) {
+	const insertQuery = `
+    INSERT INTO rate_limits
+      (key, tokens, updated_at, full_at)
+    VALUES
+      ($1, $2, NOW(), NOW())
+    ON CONFLICT (key) DO NOTHING`
+	const selectQuery = `
+    SELECT tokens, updated_at, clock_timestamp() AS now
+    FROM rate_limits
+    WHERE key = $1
+    FOR UPDATE`
+	const updateQuery = `
+    UPDATE rate_limits
+    SET tokens = $2, updated_at = $3, full_at = $4
+    WHERE key = $1`
+
+	tx, err := s.db.BeginTxx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return ratelimit.Result{}, err
+	}
+
+	if _, err := tx.ExecContext(ctx, insertQuery, key, rate.Limit); err != nil {
+		tx.Rollback()
+		return ratelimit.Result{}, err
+	}
+
+	var row struct {
+		Tokens    float64   `db:"tokens"`
+		UpdatedAt time.Time `db:"updated_at"`
+		Now       time.Time `db:"now"`
+	}
+	if err := tx.QueryRowxContext(ctx, selectQuery, key).StructScan(&row); err != nil {
+		tx.Rollback()
+		return ratelimit.Result{}, err
+	}
+
+	bucket, res := rate.Take(ratelimit.Bucket{Tokens: row.Tokens, UpdatedAt: row.UpdatedAt}, row.Now)
+
+	_, err = tx.ExecContext(ctx, updateQuery, key, bucket.Tokens, bucket.UpdatedAt, bucket.UpdatedAt.Add(res.Reset))
+	if err != nil {
+		tx.Rollback()
+		return ratelimit.Result{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return ratelimit.Result{}, err
+	}
+
+	return res, nil
+}
+
+func (s *RateLimitStore) RunEviction(ctx context.Context, interval time.Duration) {
+	runEviction(ctx, s.db, interval, `DELETE FROM rate_limits WHERE full_at < NOW()`)
+}
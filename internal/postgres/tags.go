@@ -73,7 +73,10 @@ func (s *Storage) saveTags(
 	return err
 }
 
-func (s *Storage) SelectTags(ctx context.Context) ([]string, error) {
+func (s *Storage) SelectTags(ctx context.Context) (_ []string, err error) {
+	ctx, done := s.begin(ctx, "SelectTags")
+	defer done(&err)
+
 	const query = `SELECT value FROM tags`
 	rows, err := s.db.QueryxContext(ctx, query)
 	if err != nil {
@@ -0,0 +1,145 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/askerdev/realworld-clone-go/internal/domain/entity"
+	"github.com/askerdev/realworld-clone-go/pkg/simplejwt"
+	"github.com/guregu/null/v5"
+)
+
+type InsertPersonalAccessTokenParams struct {
+	UserID    uint64
+	Name      string
+	TokenHash string
+	Scopes    []string
+	ExpiresAt null.Time
+}
+
+func (s *Storage) InsertPersonalAccessToken(
+	ctx context.Context,
+	params *InsertPersonalAccessTokenParams,
+) (_ *entity.PersonalAccessToken, err error) {
+	ctx, done := s.begin(ctx, "InsertPersonalAccessToken")
+	defer done(&err)
+
+	const query = `
+    INSERT INTO personal_access_tokens
+      (user_id, name, token_hash, scopes, expires_at)
+    VALUES
+      ($1, $2, $3, $4, $5)
+    RETURNING *`
+
+	row := s.db.QueryRowxContext(
+		ctx,
+		query,
+		params.UserID, params.Name, params.TokenHash,
+		strings.Join(params.Scopes, " "), params.ExpiresAt,
+	)
+	tokenRow := &PersonalAccessTokenRow{}
+	if err := row.StructScan(tokenRow); err != nil {
+		if isUniqueViolation(err) {
+			return nil, ErrUniqueConstraint
+		}
+		return nil, err
+	}
+
+	return convertPersonalAccessTokenRowToDomain(tokenRow), nil
+}
+
+func (s *Storage) SelectPersonalAccessTokens(
+	ctx context.Context,
+	userID uint64,
+) (_ []*entity.PersonalAccessToken, err error) {
+	ctx, done := s.begin(ctx, "SelectPersonalAccessTokens")
+	defer done(&err)
+
+	const query = `
+    SELECT * FROM personal_access_tokens
+    WHERE user_id = $1
+    ORDER BY created_at DESC`
+
+	rows, err := s.db.QueryxContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	tokens := []*entity.PersonalAccessToken{}
+	for rows.Next() {
+		tokenRow := &PersonalAccessTokenRow{}
+		if err := rows.StructScan(tokenRow); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		tokens = append(tokens, convertPersonalAccessTokenRowToDomain(tokenRow))
+	}
+
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+
+	return tokens, nil
+}
+
+func (s *Storage) DeletePersonalAccessToken(
+	ctx context.Context,
+	userID uint64,
+	tokenID uint64,
+) (err error) {
+	ctx, done := s.begin(ctx, "DeletePersonalAccessToken")
+	defer done(&err)
+
+	const query = `DELETE FROM personal_access_tokens WHERE id = $1 AND user_id = $2`
+
+	res, err := s.db.ExecContext(ctx, query, tokenID, userID)
+	if err != nil {
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func (s *Storage) LookupPersonalAccessToken(
+	ctx context.Context,
+	hash string,
+) (_ *simplejwt.PersonalAccessToken, err error) {
+	ctx, done := s.begin(ctx, "LookupPersonalAccessToken")
+	defer done(&err)
+
+	const query = `
+    UPDATE personal_access_tokens p SET last_used_at = NOW()
+    FROM users u
+    WHERE p.token_hash = $1 AND (p.expires_at IS NULL OR p.expires_at > NOW())
+      AND u.id = p.user_id AND u.suspended_at IS NULL
+    RETURNING p.*`
+
+	tokenRow := &PersonalAccessTokenRow{}
+	if err := s.db.QueryRowxContext(ctx, query, hash).StructScan(tokenRow); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, simplejwt.ErrInvalidPersonalAccessToken
+		}
+		return nil, err
+	}
+
+	pat := &simplejwt.PersonalAccessToken{
+		UserID: tokenRow.UserID,
+		Scopes: strings.Fields(tokenRow.Scopes),
+	}
+	if tokenRow.ExpiresAt.Valid {
+		pat.ExpiresAt = &tokenRow.ExpiresAt.Time
+	}
+
+	return pat, nil
+}
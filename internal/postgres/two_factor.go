@@ -0,0 +1,243 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+func (s *Storage) SelectTOTP(ctx context.Context, userID uint64) (_ *TOTPRow, err error) {
+	ctx, done := s.begin(ctx, "SelectTOTP")
+	defer done(&err)
+
+	const query = `SELECT * FROM user_totp WHERE user_id = $1`
+
+	row := &TOTPRow{}
+	if err := s.db.QueryRowxContext(ctx, query, userID).StructScan(row); err != nil {
+		return nil, err
+	}
+
+	return row, nil
+}
+
+// SavePendingTOTP stores a new secret awaiting confirmation. An already
+// enabled secret is left untouched and ErrUniqueConstraint is returned.
+func (s *Storage) SavePendingTOTP(ctx context.Context, userID uint64, secret string) (err error) {
+	ctx, done := s.begin(ctx, "SavePendingTOTP")
+	defer done(&err)
+
+	const query = `
+    INSERT INTO user_totp
+      (user_id, secret)
+    VALUES
+      ($1, $2)
+    ON CONFLICT (user_id) DO UPDATE
+    SET secret = EXCLUDED.secret, last_used_step = 0, created_at = NOW()
+    WHERE user_totp.enabled_at IS NULL`
+
+	res, err := s.db.ExecContext(ctx, query, userID, secret)
+	if err != nil {
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return ErrUniqueConstraint
+	}
+
+	return nil
+}
+
+func (s *Storage) EnableTOTP(
+	ctx context.Context,
+	userID uint64,
+	step int64,
+	recoveryCodeHashes []string,
+) (err error) {
+	ctx, done := s.begin(ctx, "EnableTOTP")
+	defer done(&err)
+
+	tx, err := s.db.BeginTxx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return err
+	}
+
+	const enableQuery = `
+    UPDATE user_totp SET enabled_at = NOW(), last_used_step = $2
+    WHERE user_id = $1 AND enabled_at IS NULL`
+
+	res, err := tx.ExecContext(ctx, enableQuery, userID, step)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if affected == 0 {
+		tx.Rollback()
+		return ErrNotFound
+	}
+
+	if err := s.replaceRecoveryCodes(ctx, tx, userID, recoveryCodeHashes); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *Storage) replaceRecoveryCodes(
+	ctx context.Context,
+	tx *sqlx.Tx,
+	userID uint64,
+	hashes []string,
+) error {
+	const deleteQuery = `DELETE FROM user_recovery_codes WHERE user_id = $1`
+
+	if _, err := tx.ExecContext(ctx, deleteQuery, userID); err != nil {
+		return err
+	}
+
+	const insertQuery = `INSERT INTO user_recovery_codes (user_id, hash) VALUES ($1, $2)`
+
+	for _, hash := range hashes {
+		if _, err := tx.ExecContext(ctx, insertQuery, userID, hash); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *Storage) DisableTOTP(ctx context.Context, userID uint64) (err error) {
+	ctx, done := s.begin(ctx, "DisableTOTP")
+	defer done(&err)
+
+	tx, err := s.db.BeginTxx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return err
+	}
+
+	if err := s.replaceRecoveryCodes(ctx, tx, userID, nil); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	const deleteQuery = `DELETE FROM user_totp WHERE user_id = $1`
+
+	if _, err := tx.ExecContext(ctx, deleteQuery, userID); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// UseTOTPStep records step as used and reports false when it, or a later
+// step, was already accepted, which stops a code from being replayed.
+func (s *Storage) UseTOTPStep(ctx context.Context, userID uint64, step int64) (_ bool, err error) {
+	ctx, done := s.begin(ctx, "UseTOTPStep")
+	defer done(&err)
+
+	const query = `
+    UPDATE user_totp SET last_used_step = $2
+    WHERE user_id = $1 AND last_used_step < $2`
+
+	return s.execAffected(ctx, query, userID, step)
+}
+
+func (s *Storage) UseRecoveryCode(ctx context.Context, userID uint64, hash string) (_ bool, err error) {
+	ctx, done := s.begin(ctx, "UseRecoveryCode")
+	defer done(&err)
+
+	const query = `
+    UPDATE user_recovery_codes SET used_at = NOW()
+    WHERE user_id = $1 AND hash = $2 AND used_at IS NULL`
+
+	return s.execAffected(ctx, query, userID, hash)
+}
+
+func (s *Storage) InsertTwoFactorChallenge(
+	ctx context.Context,
+	userID uint64,
+	hash string,
+	expiresAt time.Time,
+) (err error) {
+	ctx, done := s.begin(ctx, "InsertTwoFactorChallenge")
+	defer done(&err)
+
+	const query = `
+    INSERT INTO two_factor_challenges
+      (hash, user_id, expires_at)
+    VALUES
+      ($1, $2, $3)`
+
+	_, err = s.db.ExecContext(ctx, query, hash, userID, expiresAt)
+	return err
+}
+
+// AttemptTwoFactorChallenge counts an attempt against a challenge live at
+// now and returns its user. Expired or exhausted challenges give
+// ErrNotFound.
+func (s *Storage) AttemptTwoFactorChallenge(
+	ctx context.Context,
+	hash string,
+	maxAttempts int,
+	now time.Time,
+) (_ uint64, err error) {
+	ctx, done := s.begin(ctx, "AttemptTwoFactorChallenge")
+	defer done(&err)
+
+	const query = `
+    UPDATE two_factor_challenges SET attempts = attempts + 1
+    WHERE hash = $1 AND expires_at > $3 AND attempts < $2
+    RETURNING user_id`
+
+	var userID uint64
+	if err := s.db.QueryRowxContext(ctx, query, hash, maxAttempts, now).Scan(&userID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, ErrNotFound
+		}
+		return 0, err
+	}
+
+	return userID, nil
+}
+
+// DeleteTwoFactorChallenge deletes the challenge, along with any that have
+// expired by now.
+func (s *Storage) DeleteTwoFactorChallenge(ctx context.Context, hash string, now time.Time) (err error) {
+	ctx, done := s.begin(ctx, "DeleteTwoFactorChallenge")
+	defer done(&err)
+
+	const query = `DELETE FROM two_factor_challenges WHERE hash = $1 OR expires_at < $2`
+
+	_, err = s.db.ExecContext(ctx, query, hash, now)
+	return err
+}
+
+func (s *Storage) execAffected(ctx context.Context, query string, args ...any) (bool, error) {
+	res, err := s.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected > 0, nil
+}
@@ -13,11 +13,14 @@ import (
 func (r *Storage) InsertUser(
 	ctx context.Context,
 	email, username, password string,
-) (*entity.User, error) {
+) (_ *entity.User, err error) {
+	ctx, done := r.begin(ctx, "InsertUser")
+	defer done(&err)
+
 	const query = `
     INSERT INTO users (email, username, password)
     VALUES ($1, $2, $3)
-    RETURNING id, email, username, bio, image
+    RETURNING id, email, username, bio, image, email_verified_at, role, created_at, last_login_at, suspended_at
     `
 	row := r.db.QueryRowxContext(ctx, query, email, username, password)
 
@@ -38,7 +41,10 @@ func (r *Storage) FollowProfile(
 	ctx context.Context,
 	userId uint64,
 	username string,
-) (*entity.Profile, error) {
+) (_ *entity.Profile, err error) {
+	ctx, done := r.begin(ctx, "FollowProfile")
+	defer done(&err)
+
 	const createSubscriptionQuery = `
     INSERT INTO subscriptions
       (user_id, profile_id)
@@ -84,7 +90,10 @@ func (r *Storage) UnfollowProfile(
 	ctx context.Context,
 	userId uint64,
 	username string,
-) (*entity.Profile, error) {
+) (_ *entity.Profile, err error) {
+	ctx, done := r.begin(ctx, "UnfollowProfile")
+	defer done(&err)
+
 	const removeSubscriptionsQuery = `
     DELETE FROM subscriptions
     WHERE user_id = $1 AND profile_id = $2`
@@ -147,7 +156,10 @@ func (r *Storage) SelectProfileByID(
 	ctx context.Context,
 	profileID uint64,
 	userID *uint64,
-) (*entity.Profile, error) {
+) (_ *entity.Profile, err error) {
+	ctx, done := r.begin(ctx, "SelectProfileByID")
+	defer done(&err)
+
 	const selectProfileByIDQuery = `SELECT id, username, image, bio FROM users WHERE id = $1`
 
 	tx, err := r.db.BeginTxx(ctx, &sql.TxOptions{})
@@ -182,7 +194,10 @@ func (r *Storage) SelectProfileByUsername(
 	ctx context.Context,
 	username string,
 	userID *uint64,
-) (*entity.Profile, error) {
+) (_ *entity.Profile, err error) {
+	ctx, done := r.begin(ctx, "SelectProfileByUsername")
+	defer done(&err)
+
 	const selectProfileByUsernameQuery = `SELECT id, username, image, bio FROM users WHERE username = $1`
 	const selectSubscription = `SELECT * FROM subscriptions WHERE user_id = $1 AND profile_id = $2`
 
@@ -216,7 +231,10 @@ func (r *Storage) SelectProfileByUsername(
 func (r *Storage) SelectUserByEmail(
 	ctx context.Context,
 	email string,
-) (*entity.User, error) {
+) (_ *entity.User, err error) {
+	ctx, done := r.begin(ctx, "SelectUserByEmail")
+	defer done(&err)
+
 	const query = `SELECT * FROM users WHERE email = $1`
 	row := r.db.QueryRowxContext(ctx, query, email)
 	if row.Err() != nil {
@@ -230,6 +248,116 @@ func (r *Storage) SelectUserByEmail(
 	return u, nil
 }
 
+func (r *Storage) SelectUserByID(
+	ctx context.Context,
+	id uint64,
+) (_ *entity.User, err error) {
+	ctx, done := r.begin(ctx, "SelectUserByID")
+	defer done(&err)
+
+	const query = `SELECT * FROM users WHERE id = $1`
+	row := r.db.QueryRowxContext(ctx, query, id)
+	if row.Err() != nil {
+		return nil, row.Err()
+	}
+	u := &entity.User{}
+	if err := row.StructScan(u); err != nil {
+		return nil, err
+	}
+
+	return u, nil
+}
+
+// SelectActiveUserByID is SelectUserByID for users that may authenticate:
+// suspended users come back as sql.ErrNoRows.
+func (r *Storage) SelectActiveUserByID(
+	ctx context.Context,
+	id uint64,
+) (_ *entity.User, err error) {
+	ctx, done := r.begin(ctx, "SelectActiveUserByID")
+	defer done(&err)
+
+	const query = `SELECT * FROM users WHERE id = $1 AND suspended_at IS NULL`
+	u := &entity.User{}
+	if err := r.db.QueryRowxContext(ctx, query, id).StructScan(u); err != nil {
+		return nil, err
+	}
+
+	return u, nil
+}
+
+// SelectUserByLogin finds a user by email or username.
+func (r *Storage) SelectUserByLogin(
+	ctx context.Context,
+	login string,
+) (_ *entity.User, err error) {
+	ctx, done := r.begin(ctx, "SelectUserByLogin")
+	defer done(&err)
+
+	const query = `SELECT * FROM users WHERE email = $1 OR username = $1`
+	u := &entity.User{}
+	if err := r.db.QueryRowxContext(ctx, query, login).StructScan(u); err != nil {
+		return nil, err
+	}
+
+	return u, nil
+}
+
+func (r *Storage) SetUserRole(ctx context.Context, id uint64, role entity.Role) (err error) {
+	ctx, done := r.begin(ctx, "SetUserRole")
+	defer done(&err)
+
+	const query = `UPDATE users SET role = $2 WHERE id = $1`
+	res, err := r.db.ExecContext(ctx, query, id, role)
+	if err != nil {
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func (r *Storage) MarkEmailVerified(ctx context.Context, id uint64) (err error) {
+	ctx, done := r.begin(ctx, "MarkEmailVerified")
+	defer done(&err)
+
+	const query = `UPDATE users SET email_verified_at = COALESCE(email_verified_at, NOW()) WHERE id = $1`
+	_, err = r.db.ExecContext(ctx, query, id)
+	return err
+}
+
+func (r *Storage) TouchLastLogin(ctx context.Context, id uint64) (err error) {
+	ctx, done := r.begin(ctx, "TouchLastLogin")
+	defer done(&err)
+
+	const query = `UPDATE users SET last_login_at = NOW() WHERE id = $1`
+	_, err = r.db.ExecContext(ctx, query, id)
+	return err
+}
+
+// RehashPassword swaps the stored hash only if it is still oldHash, so a
+// password changed in the meantime is never overwritten.
+func (r *Storage) RehashPassword(
+	ctx context.Context,
+	id uint64,
+	oldHash, newHash string,
+) (err error) {
+	ctx, done := r.begin(ctx, "RehashPassword")
+	defer done(&err)
+
+	const query = `UPDATE users SET password = $3 WHERE id = $1 AND password = $2`
+	_, err = r.db.ExecContext(ctx, query, id, oldHash, newHash)
+	return err
+}
+
 type UpdateUserParams struct {
 	ID       uint64
 	Email    null.String `db:"email"`
@@ -242,11 +370,15 @@ type UpdateUserParams struct {
 func (r *Storage) UpdateUser(
 	ctx context.Context,
 	updateUserParams *UpdateUserParams,
-) (*entity.User, error) {
+) (_ *entity.User, err error) {
+	ctx, done := r.begin(ctx, "UpdateUser")
+	defer done(&err)
+
 	fields := []string{}
 
 	if updateUserParams.Email.Valid {
 		fields = append(fields, "email = :email")
+		fields = append(fields, "email_verified_at = CASE WHEN email = :email THEN email_verified_at END")
 	}
 
 	if updateUserParams.Username.Valid {
@@ -269,7 +401,7 @@ func (r *Storage) UpdateUser(
     UPDATE users SET ` +
 		strings.Join(fields, ",") +
 		` WHERE id = :id
-      RETURNING id, email, username, bio, image`
+      RETURNING id, email, username, bio, image, email_verified_at, role, created_at, last_login_at, suspended_at`
 	rows, err := r.db.NamedQueryContext(ctx, query, updateUserParams)
 	if err != nil {
 		return nil, err
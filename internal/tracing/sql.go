@@ -0,0 +1,192 @@
+package tracing
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"strings"
+
+	"github.com/XSAM/otelsql"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// OpenDB opens a Postgres database whose statements become spans under the
+// span in the query's context. Statements run outside of a trace, such as
+// background evictions, are not traced. Spans carry the statement text
+// with literals redacted and never the arguments.
+func OpenDB(driverName, dsn string) (*sql.DB, error) {
+	return otelsql.Open(
+		driverName,
+		dsn,
+		otelsql.WithAttributes(semconv.DBSystemPostgreSQL),
+		otelsql.WithSpanOptions(otelsql.SpanOptions{
+			DisableQuery:         true,
+			OmitConnResetSession: true,
+			OmitRows:             true,
+			SpanFilter: func(ctx context.Context, _ otelsql.Method, _ string, _ []driver.NamedValue) bool {
+				return trace.SpanContextFromContext(ctx).IsValid()
+			},
+		}),
+		otelsql.WithAttributesGetter(func(_ context.Context, _ otelsql.Method, query string, _ []driver.NamedValue) []attribute.KeyValue {
+			if query == "" {
+				return nil
+			}
+			return []attribute.KeyValue{semconv.DBQueryText(RedactSQL(query))}
+		}),
+	)
+}
+
+// RedactSQL replaces string and number literals with ?. Arguments bound
+// to $n placeholders never reach the statement text in the first place.
+// Identifiers, quoted or not, placeholders and comments are kept as they
+// are; a quote inside a comment doesn't start a string.
+func RedactSQL(query string) string {
+	var b strings.Builder
+	b.Grow(len(query))
+
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case strings.HasPrefix(query[i:], "--"):
+			j := len(query)
+			if end := strings.IndexByte(query[i:], '\n'); end >= 0 {
+				j = i + end
+			}
+			b.WriteString(query[i:j])
+			i = j
+			break
+		case strings.HasPrefix(query[i:], "/*"):
+			j := len(query)
+			if end := strings.Index(query[i+2:], "*/"); end >= 0 {
+				j = i + 2 + end + 2
+			}
+			b.WriteString(query[i:j])
+			i = j
+			break
+		case c == '\'':
+			i = skipString(query, i+1, false)
+			b.WriteByte('?')
+			break
+		case (c == 'E' || c == 'e') && i+1 < len(query) && query[i+1] == '\'':
+			i = skipString(query, i+2, true)
+			b.WriteByte('?')
+			break
+		case c == '"':
+			j := len(query)
+			if end := strings.IndexByte(query[i+1:], '"'); end >= 0 {
+				j = i + 1 + end + 1
+			}
+			b.WriteString(query[i:j])
+			i = j
+			break
+		case c == '$':
+			if tag, ok := dollarTag(query[i:]); ok {
+				end := strings.Index(query[i+len(tag):], tag)
+				if end < 0 {
+					i = len(query)
+				} else {
+					i += len(tag) + end + len(tag)
+				}
+				b.WriteByte('?')
+				break
+			}
+			// a $n placeholder
+			j := i + 1
+			for j < len(query) && isDigit(query[j]) {
+				j++
+			}
+			b.WriteString(query[i:j])
+			i = j
+			break
+		case isIdentStart(c):
+			j := i + 1
+			for j < len(query) && (isIdentStart(query[j]) || isDigit(query[j]) || query[j] == '$') {
+				j++
+			}
+			b.WriteString(query[i:j])
+			i = j
+			break
+		case isDigit(c) || (c == '.' && i+1 < len(query) && isDigit(query[i+1])):
+			i = skipNumber(query, i)
+			b.WriteByte('?')
+			break
+		default:
+			b.WriteByte(c)
+			i++
+			break
+		}
+	}
+
+	return b.String()
+}
+
+// skipString returns the index after the string literal whose body starts
+// at i. A doubled quote is an escaped one, and in E strings so is a quote
+// after a backslash.
+func skipString(query string, i int, backslash bool) int {
+	for i < len(query) {
+		switch {
+		case backslash && query[i] == '\\':
+			i += 2
+			break
+		case query[i] == '\'':
+			if i+1 < len(query) && query[i+1] == '\'' {
+				i += 2
+				break
+			}
+			return i + 1
+		default:
+			i++
+			break
+		}
+	}
+
+	return len(query)
+}
+
+// dollarTag returns the $tag$ or $$ opening a dollar-quoted string at the
+// start of s.
+func dollarTag(s string) (string, bool) {
+	for j := 1; j < len(s); j++ {
+		switch {
+		case s[j] == '$':
+			return s[:j+1], true
+		case isIdentStart(s[j]) || (j > 1 && isDigit(s[j])):
+			continue
+		default:
+			return "", false
+		}
+	}
+
+	return "", false
+}
+
+func skipNumber(query string, i int) int {
+	for i < len(query) && (isDigit(query[i]) || query[i] == '.') {
+		i++
+	}
+	if i < len(query) && (query[i] == 'e' || query[i] == 'E') {
+		j := i + 1
+		if j < len(query) && (query[j] == '+' || query[j] == '-') {
+			j++
+		}
+		if j < len(query) && isDigit(query[j]) {
+			i = j
+			for i < len(query) && isDigit(query[i]) {
+				i++
+			}
+		}
+	}
+
+	return i
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || c >= 0x80
+}
@@ -0,0 +1,37 @@
+package tracing
+
+import "testing"
+
+func TestRedactSQL(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{"placeholders", `SELECT * FROM users WHERE id = $1 AND email = $2`, `SELECT * FROM users WHERE id = $1 AND email = $2`},
+		{"string", `SELECT * FROM users WHERE email = 'a@b.c'`, `SELECT * FROM users WHERE email = ?`},
+		{"escaped quote", `SELECT 'it''s', 'x'`, `SELECT ?, ?`},
+		{"empty string", `SELECT ''`, `SELECT ?`},
+		{"backslash escape", `SELECT E'it\'s', E'a\\', 'b'`, `SELECT ?, ?, ?`},
+		{"backslash in plain string", `SELECT 'a\', 1`, `SELECT ?, ?`},
+		{"dollar quoted", `SELECT $$it's 42$$, 1`, `SELECT ?, ?`},
+		{"tagged dollar quoted", `SELECT $fn$ a $$ b $fn$, $1`, `SELECT ?, $1`},
+		{"numbers", `SELECT 42, 3.14, .5, 1e10, 2.5E-3`, `SELECT ?, ?, ?, ?, ?`},
+		{"negative number", `SELECT -7`, `SELECT -?`},
+		{"digits in identifiers", `SELECT col1, t2.x3, v$4 FROM t2`, `SELECT col1, t2.x3, v$4 FROM t2`},
+		{"quoted identifier", `SELECT "col 1", "it's" FROM t`, `SELECT "col 1", "it's" FROM t`},
+		{"interval", `NOW() - INTERVAL '15 minutes'`, `NOW() - INTERVAL ?`},
+		{"line comment", "SELECT 1 -- it's 2\nFROM t", "SELECT ? -- it's 2\nFROM t"},
+		{"block comment", `SELECT /* it's 2 */ 'x'`, `SELECT /* it's 2 */ ?`},
+		{"unterminated string", `SELECT 'secret`, `SELECT ?`},
+		{"unterminated dollar quote", `SELECT $$secret`, `SELECT ?`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RedactSQL(tt.query); got != tt.want {
+				t.Errorf("RedactSQL(%q) = %q, want %q", tt.query, got, tt.want)
+			}
+		})
+	}
+}
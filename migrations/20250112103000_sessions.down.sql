@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS refresh_tokens CASCADE;
+DROP TABLE IF EXISTS jwt_sessions CASCADE;
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS two_factor_challenges CASCADE;
+DROP TABLE IF EXISTS user_recovery_codes CASCADE;
+DROP TABLE IF EXISTS user_totp CASCADE;
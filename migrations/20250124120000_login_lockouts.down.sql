@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS lockout_events CASCADE;
+DROP TABLE IF EXISTS login_failures CASCADE;
@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS login_failures (
+  key TEXT PRIMARY KEY,
+  failures INT NOT NULL DEFAULT 0,
+  last_failure_at TIMESTAMPTZ NOT NULL,
+  locked_until TIMESTAMPTZ
+);
+
+CREATE TABLE IF NOT EXISTS lockout_events (
+  id BIGSERIAL PRIMARY KEY,
+  key TEXT NOT NULL,
+  user_id BIGINT REFERENCES users(id) ON DELETE SET NULL,
+  ip TEXT NOT NULL,
+  failures INT NOT NULL,
+  locked_until TIMESTAMPTZ NOT NULL,
+  unlocked_at TIMESTAMPTZ,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS lockout_events_user_id_idx ON lockout_events (user_id, created_at);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS users_created_at_idx;
+
+ALTER TABLE users DROP COLUMN IF EXISTS suspended_at;
+ALTER TABLE users DROP COLUMN IF EXISTS last_login_at;
+ALTER TABLE users DROP COLUMN IF EXISTS created_at;
@@ -0,0 +1,142 @@
+// Package ratelimit limits requests with token buckets kept in a Store,
+// and reports the limit in RateLimit-* headers.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Rate allows Limit requests per Period. A bucket holds Limit tokens and
+// refills continuously, so bursts of up to Limit requests are allowed.
+// A zero Rate means no limit.
+type Rate struct {
+	Limit  int
+	Period time.Duration
+}
+
+func (r Rate) Enabled() bool {
+	return r.Limit > 0 && r.Period > 0
+}
+
+func (r Rate) String() string {
+	if !r.Enabled() {
+		return "off"
+	}
+
+	return strconv.Itoa(r.Limit) + "/" + r.Period.String()
+}
+
+func (r Rate) perToken() time.Duration {
+	return r.Period / time.Duration(r.Limit)
+}
+
+// Bucket is what a Store keeps per key. The zero Bucket is full.
+type Bucket struct {
+	Tokens    float64
+	UpdatedAt time.Time
+}
+
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// RetryAfter is how long until the next token, when not allowed.
+	RetryAfter time.Duration
+	// Reset is how long until the bucket is full again.
+	Reset time.Duration
+}
+
+// Take refills b for the time passed since its last update and takes a
+// token from it if there is one.
+func (r Rate) Take(b Bucket, now time.Time) (Bucket, Result) {
+	limit := float64(r.Limit)
+	perToken := float64(r.perToken())
+
+	tokens := limit
+	if !b.UpdatedAt.IsZero() {
+		elapsed := max(now.Sub(b.UpdatedAt), 0)
+		tokens = math.Min(limit, b.Tokens+float64(elapsed)/perToken)
+	}
+
+	res := Result{Limit: r.Limit}
+	if tokens >= 1 {
+		tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = time.Duration((1 - tokens) * perToken)
+	}
+	res.Remaining = int(tokens)
+	res.Reset = time.Duration((limit - tokens) * perToken)
+
+	return Bucket{Tokens: tokens, UpdatedAt: now}, res
+}
+
+// Store keeps buckets by key. Take applies rate to the bucket under key
+// atomically, as replicas sharing a store may take from it concurrently.
+type Store interface {
+	Take(ctx context.Context, key string, rate Rate) (Result, error)
+}
+
+// DenyFunc writes the response for a limited request.
+type DenyFunc func(w http.ResponseWriter, r *http.Request, res Result)
+
+// ErrorFunc is told when the store fails. The request is let through then,
+// so an unavailable store doesn't take the API down with it.
+type ErrorFunc func(r *http.Request, err error)
+
+type Limiter struct {
+	store   Store
+	deny    DenyFunc
+	onError ErrorFunc
+}
+
+func New(store Store, deny DenyFunc, onError ErrorFunc) *Limiter {
+	return &Limiter{
+		store:   store,
+		deny:    deny,
+		onError: onError,
+	}
+}
+
+// Middleware limits requests under the named policy, keeping a bucket per
+// policy and key(r). A disabled rate lets everything through.
+func (l *Limiter) Middleware(policy string, rate Rate, key func(r *http.Request) string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if !rate.Enabled() {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			res, err := l.store.Take(r.Context(), policy+":"+key(r), rate)
+			if err != nil {
+				l.onError(r, err)
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			header := w.Header()
+			header.Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", rate.Limit, seconds(rate.Period)))
+			header.Set("RateLimit-Limit", strconv.Itoa(res.Limit))
+			header.Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+			header.Set("RateLimit-Reset", strconv.Itoa(seconds(res.Reset)))
+
+			if !res.Allowed {
+				header.Set("Retry-After", strconv.Itoa(seconds(res.RetryAfter)))
+				l.deny(w, r, res)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// seconds rounds up, so clients never come back too early.
+func seconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
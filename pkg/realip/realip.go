@@ -0,0 +1,75 @@
+// Package realip finds the address of the client behind trusted proxies.
+package realip
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+type Resolver struct {
+	trusted []netip.Prefix
+}
+
+// New trusts the proxies in the given CIDRs or single addresses. With none,
+// X-Forwarded-For is ignored and the peer address is the client.
+func New(trustedProxies ...string) (*Resolver, error) {
+	r := &Resolver{}
+	for _, proxy := range trustedProxies {
+		prefix, err := netip.ParsePrefix(proxy)
+		if err != nil {
+			addr, addrErr := netip.ParseAddr(proxy)
+			if addrErr != nil {
+				return nil, fmt.Errorf("trusted proxy %q is not an address or CIDR", proxy)
+			}
+			prefix = netip.PrefixFrom(addr, addr.BitLen())
+		}
+		r.trusted = append(r.trusted, prefix.Masked())
+	}
+
+	return r, nil
+}
+
+// ClientIP returns the peer address, unless the peer is a trusted proxy.
+// Then X-Forwarded-For is walked from the right, skipping trusted proxies,
+// and the first address that isn't one is the client. Entries left of it
+// were set by the client and can't be trusted.
+func (r *Resolver) ClientIP(req *http.Request) string {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		host = req.RemoteAddr
+	}
+
+	peer, err := netip.ParseAddr(host)
+	if err != nil || !r.isTrusted(peer) {
+		return host
+	}
+
+	hops := strings.Split(strings.Join(req.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		addr, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			break
+		}
+
+		if !r.isTrusted(addr) {
+			return addr.String()
+		}
+		host = addr.String()
+	}
+
+	return host
+}
+
+func (r *Resolver) isTrusted(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	for _, prefix := range r.trusted {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+
+	return false
+}
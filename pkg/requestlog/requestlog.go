@@ -0,0 +1,139 @@
+// Package requestlog tags every request with an ID, hands handlers a logger
+// that carries it and writes one access line per request.
+package requestlog
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/askerdev/realworld-clone-go/pkg/router"
+)
+
+// Header carries the request ID, both ways.
+const Header = "X-Request-ID"
+
+const maxIDLen = 128
+
+type ctxKey struct{}
+
+type entry struct {
+	id     string
+	base   *slog.Logger
+	ctx    context.Context
+	userID uint64
+}
+
+// logger adds what is only known once the request went further down the
+// chain: the route that matched, and the user the auth middleware reports
+// through SetUser.
+func (e *entry) logger() *slog.Logger {
+	l := e.base
+	if pattern := router.Pattern(e.ctx); pattern != "" {
+		l = l.With(slog.String("route", pattern))
+	}
+	if e.userID != 0 {
+		l = l.With(slog.Uint64("userId", e.userID))
+	}
+
+	return l
+}
+
+// Middleware assigns a request ID, or keeps a well-formed one sent by the
+// client, echoes it in the response and logs every request through base.
+func Middleware(base *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+
+			id := r.Header.Get(Header)
+			if !validID(id) {
+				id = newID()
+			}
+			w.Header().Set(Header, id)
+
+			e := &entry{
+				id: id,
+				base: base.With(
+					slog.String("requestId", id),
+					slog.String("method", r.Method),
+				),
+			}
+			e.ctx = context.WithValue(r.Context(), ctxKey{}, e)
+
+			sw := router.NewStatusWriter(w)
+			next.ServeHTTP(sw, r.WithContext(e.ctx))
+
+			status := sw.Status()
+
+			level := slog.LevelInfo
+			if status >= http.StatusInternalServerError {
+				level = slog.LevelError
+			}
+
+			e.logger().LogAttrs(
+				r.Context(),
+				level,
+				"request",
+				slog.String("path", r.URL.Path),
+				slog.Int("status", status),
+				slog.Int64("bytes", sw.Bytes()),
+				slog.Duration("latency", time.Since(start)),
+			)
+		})
+	}
+}
+
+// FromContext returns the request's logger, or the default logger outside
+// of a request.
+func FromContext(ctx context.Context) *slog.Logger {
+	e, ok := ctx.Value(ctxKey{}).(*entry)
+	if !ok {
+		return slog.Default()
+	}
+
+	return e.logger()
+}
+
+// ID returns the request ID, or "" outside of a request.
+func ID(ctx context.Context) string {
+	e, ok := ctx.Value(ctxKey{}).(*entry)
+	if !ok {
+		return ""
+	}
+
+	return e.id
+}
+
+// SetUser attaches the authenticated user to the request's log lines.
+func SetUser(ctx context.Context, userID uint64) {
+	if e, ok := ctx.Value(ctxKey{}).(*entry); ok {
+		e.userID = userID
+	}
+}
+
+func validID(id string) bool {
+	if id == "" || len(id) > maxIDLen {
+		return false
+	}
+
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-', c == '_', c == '.', c == ':':
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
+func newID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
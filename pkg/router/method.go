@@ -0,0 +1,17 @@
+package router
+
+import "net/http"
+
+// Method returns r's method, or "OTHER" for anything outside the standard
+// set, so metric labels and span names built from it stay bounded whatever
+// clients send.
+func Method(r *http.Request) string {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut,
+		http.MethodPatch, http.MethodDelete, http.MethodConnect,
+		http.MethodOptions, http.MethodTrace:
+		return r.Method
+	default:
+		return "OTHER"
+	}
+}
@@ -0,0 +1,110 @@
+// Package router registers routes on an http.ServeMux once, wrapping them
+// in middleware applied globally, per group of routes and per route.
+package router
+
+import (
+	"context"
+	"net/http"
+)
+
+type Middleware func(http.Handler) http.Handler
+
+// Chain is a list of middleware; the first one is the outermost.
+type Chain []Middleware
+
+func (c Chain) Then(h http.Handler) http.Handler {
+	for i := len(c) - 1; i >= 0; i-- {
+		h = c[i](h)
+	}
+
+	return h
+}
+
+// Append returns a new chain, leaving c untouched.
+func (c Chain) Append(m ...Middleware) Chain {
+	return append(c[:len(c):len(c)], m...)
+}
+
+type Router struct {
+	mux     *http.ServeMux
+	global  Chain
+	handler http.Handler
+}
+
+func New() *Router {
+	mux := http.NewServeMux()
+
+	return &Router{
+		mux:     mux,
+		handler: mux,
+	}
+}
+
+// Use adds global middleware. It runs for every request, before routing,
+// so it also sees requests that end up as 404 or 405.
+func (r *Router) Use(m ...Middleware) {
+	r.global = r.global.Append(m...)
+	r.handler = r.global.Then(r.mux)
+}
+
+type routeKey struct{}
+
+type route struct {
+	pattern string
+}
+
+func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	ctx := context.WithValue(req.Context(), routeKey{}, &route{})
+	r.handler.ServeHTTP(w, req.WithContext(ctx))
+}
+
+// Pattern returns the pattern of the route that matched. Global middleware
+// can't read it off its own request, since ServeMux only sets it on the copy
+// it is handed, but can call Pattern once the request has been routed. It
+// is "" for requests no route matched.
+func Pattern(ctx context.Context) string {
+	if rt, ok := ctx.Value(routeKey{}).(*route); ok {
+		return rt.pattern
+	}
+
+	return ""
+}
+
+// Group starts a group whose routes all run through m.
+func (r *Router) Group(m ...Middleware) *Group {
+	return &Group{
+		mux:   r.mux,
+		chain: Chain(m),
+	}
+}
+
+type Group struct {
+	mux   *http.ServeMux
+	chain Chain
+}
+
+// Group starts a nested group that adds m after the parent's middleware.
+func (g *Group) Group(m ...Middleware) *Group {
+	return &Group{
+		mux:   g.mux,
+		chain: g.chain.Append(m...),
+	}
+}
+
+// Handle registers h for a ServeMux pattern, behind the group's middleware
+// and then the route's own.
+func (g *Group) Handle(pattern string, h http.Handler, m ...Middleware) {
+	next := g.chain.Append(m...).Then(h)
+
+	g.mux.Handle(pattern, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if rt, ok := req.Context().Value(routeKey{}).(*route); ok {
+			rt.pattern = req.Pattern
+		}
+
+		next.ServeHTTP(w, req)
+	}))
+}
+
+func (g *Group) HandleFunc(pattern string, fn http.HandlerFunc, m ...Middleware) {
+	g.Handle(pattern, fn, m...)
+}
@@ -0,0 +1,116 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+// record returns middleware that appends name to calls when it runs.
+func record(calls *[]string, name string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			*calls = append(*calls, name)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func TestMiddlewareOrder(t *testing.T) {
+	var calls []string
+
+	r := New()
+	r.Use(record(&calls, "global1"), record(&calls, "global2"))
+
+	g := r.Group(record(&calls, "group"))
+	nested := g.Group(record(&calls, "nested"))
+	nested.HandleFunc("GET /items", func(http.ResponseWriter, *http.Request) {
+		calls = append(calls, "handler")
+	}, record(&calls, "route1"), record(&calls, "route2"))
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/items", nil))
+
+	want := []string{"global1", "global2", "group", "nested", "route1", "route2", "handler"}
+	if !slices.Equal(calls, want) {
+		t.Fatalf("calls = %v, want %v", calls, want)
+	}
+}
+
+func TestChainAppendDoesNotAlias(t *testing.T) {
+	var calls []string
+
+	base := make(Chain, 1, 4)
+	base[0] = record(&calls, "base")
+
+	a := base.Append(record(&calls, "a"))
+	b := base.Append(record(&calls, "b"))
+
+	a.Then(http.NotFoundHandler()).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	if want := []string{"base", "a"}; !slices.Equal(calls, want) {
+		t.Fatalf("a ran %v, want %v", calls, want)
+	}
+
+	calls = nil
+	b.Then(http.NotFoundHandler()).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	if want := []string{"base", "b"}; !slices.Equal(calls, want) {
+		t.Fatalf("b ran %v, want %v", calls, want)
+	}
+
+	if len(base) != 1 {
+		t.Fatalf("base grew to %d middleware", len(base))
+	}
+}
+
+func TestPattern(t *testing.T) {
+	var pattern string
+
+	r := New()
+	r.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			next.ServeHTTP(w, req)
+			pattern = Pattern(req.Context())
+		})
+	})
+	r.Group().HandleFunc("GET /items/{id}", func(http.ResponseWriter, *http.Request) {})
+
+	tests := []struct {
+		method string
+		path   string
+		status int
+		want   string
+	}{
+		{http.MethodGet, "/items/1", http.StatusOK, "GET /items/{id}"},
+		{http.MethodGet, "/missing", http.StatusNotFound, ""},
+		{http.MethodPost, "/items/1", http.StatusMethodNotAllowed, ""},
+	}
+	for _, tt := range tests {
+		pattern = "unset"
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, nil))
+
+		if rec.Code != tt.status {
+			t.Errorf("%s %s = %d, want %d", tt.method, tt.path, rec.Code, tt.status)
+		}
+		if pattern != tt.want {
+			t.Errorf("%s %s: Pattern = %q, want %q", tt.method, tt.path, pattern, tt.want)
+		}
+	}
+
+	if got := Pattern(httptest.NewRequest(http.MethodGet, "/", nil).Context()); got != "" {
+		t.Errorf("Pattern outside the router = %q, want \"\"", got)
+	}
+}
+
+func TestMethod(t *testing.T) {
+	for method, want := range map[string]string{
+		http.MethodGet:    http.MethodGet,
+		http.MethodDelete: http.MethodDelete,
+		"PROPFIND":        "OTHER",
+		"get":             "OTHER",
+	} {
+		if got := Method(httptest.NewRequest(method, "/", nil)); got != want {
+			t.Errorf("Method(%q) = %q, want %q", method, got, want)
+		}
+	}
+}
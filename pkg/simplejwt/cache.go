@@ -2,7 +2,13 @@ package simplejwt
 
 import "time"
 
+// Cache stores the per-user iat watermark and the list of revoked token IDs.
+// Revoked entries only need to be kept until expiresAt, when the token
+// would have expired anyway. Get reports whether the user has a watermark;
+// an error means it couldn't tell, and tokens are then rejected.
 type Cache interface {
-	Get(key uint64) (time.Time, bool)
+	Get(key uint64) (time.Time, bool, error)
 	Set(key uint64, value time.Time) error
+	Revoke(jti string, expiresAt time.Time) error
+	Revoked(jti string) bool
 }
@@ -0,0 +1,57 @@
+package simplejwt
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type SubjectID interface {
+	uint64 | string
+}
+
+// Claims carry nothing but the registered claims; the subject ID is kept in
+// the standard "sub" claim and exposed typed through SubjectID.
+type Claims[ID SubjectID] struct {
+	jwt.RegisteredClaims
+}
+
+func NewClaims[ID SubjectID](id ID, registered jwt.RegisteredClaims) *Claims[ID] {
+	switch v := any(id).(type) {
+	case uint64:
+		registered.Subject = strconv.FormatUint(v, 10)
+	case string:
+		registered.Subject = v
+	}
+
+	return &Claims[ID]{RegisteredClaims: registered}
+}
+
+func (c *Claims[ID]) SubjectID() (ID, error) {
+	var id ID
+	switch p := any(&id).(type) {
+	case *uint64:
+		v, err := strconv.ParseUint(c.Subject, 10, 64)
+		if err != nil {
+			return id, errors.New("invalid token subject")
+		}
+		*p = v
+	case *string:
+		if c.Subject == "" {
+			return id, errors.New("invalid token subject")
+		}
+		*p = c.Subject
+	}
+
+	return id, nil
+}
+
+func TokenClaims(token *jwt.Token) (*Claims[uint64], error) {
+	claims, ok := token.Claims.(*Claims[uint64])
+	if !ok {
+		return nil, errors.New("invalid token claims")
+	}
+
+	return claims, nil
+}
@@ -12,6 +12,7 @@ type middlewareContextKey string
 
 const tokenContextKey middlewareContextKey = "token"
 const userContextKey middlewareContextKey = "user"
+const patContextKey middlewareContextKey = "pat"
 
 func ContextWithToken(ctx context.Context, token *jwt.Token) context.Context {
 	return context.WithValue(ctx, tokenContextKey, token)
@@ -21,6 +22,17 @@ func ContextWithUser(ctx context.Context, user *entity.User) context.Context {
 	return context.WithValue(ctx, userContextKey, user)
 }
 
+func ContextWithPersonalAccessToken(ctx context.Context, pat *PersonalAccessToken) context.Context {
+	return context.WithValue(ctx, patContextKey, pat)
+}
+
+// ContextPersonalAccessToken returns the PAT the request was authenticated
+// with, or nil when it was authenticated with a JWT or not at all.
+func ContextPersonalAccessToken(ctx context.Context) *PersonalAccessToken {
+	pat, _ := ctx.Value(patContextKey).(*PersonalAccessToken)
+	return pat
+}
+
 func ContextToken(ctx context.Context) (*jwt.Token, error) {
 	val := ctx.Value(tokenContextKey)
 	if val == nil {
@@ -35,11 +47,16 @@ func ContextToken(ctx context.Context) (*jwt.Token, error) {
 	return t, nil
 }
 
-func MustContextToken(ctx context.Context) *jwt.Token {
-	t, err := ContextToken(ctx)
-	if err != nil {
-		panic(err)
+func ContextUser(ctx context.Context) (*entity.User, error) {
+	val := ctx.Value(userContextKey)
+	if val == nil {
+		return nil, errors.New("no user in context")
+	}
+
+	u, ok := val.(*entity.User)
+	if !ok {
+		return nil, errors.New("unexpected user type in context")
 	}
 
-	return t
+	return u, nil
 }
@@ -1,56 +1,95 @@
 package simplejwt
 
 import (
-	"crypto"
+	"errors"
 	"fmt"
-	"os"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
 type Issuer struct {
-	key   crypto.PrivateKey
-	cache Cache
+	keyset       *Keyset
+	cache        Cache
+	refreshStore RefreshStore
+	opts         Options
 }
 
-func NewIssuer(privateKeyPath string, cache Cache) (*Issuer, error) {
-	keyBytes, err := os.ReadFile(privateKeyPath)
-	if err != nil {
-		panic(fmt.Errorf("unable to read private key file: %w", err))
-	}
-
-	key, err := jwt.ParseEdPrivateKeyFromPEM(keyBytes)
-	if err != nil {
-		return nil, fmt.Errorf("unable to parse as ed private key: %w", err)
+func NewIssuer(keyset *Keyset, cache Cache, refreshStore RefreshStore, opts Options) (*Issuer, error) {
+	if _, _, err := keyset.SigningKey(); err != nil {
+		return nil, err
 	}
 
 	return &Issuer{
-		key:   key,
-		cache: cache,
+		keyset:       keyset,
+		cache:        cache,
+		refreshStore: refreshStore,
+		opts:         opts.withDefaults(),
 	}, nil
 }
 
-func (i *Issuer) Token(authID uint64, data any) (string, error) {
-	now := time.Now()
-	token := jwt.NewWithClaims(&jwt.SigningMethodEd25519{}, jwt.MapClaims{
-		"aud": "api",
-		"nbf": now.Unix(),
-		"iat": now.Unix(),
-		"exp": now.Add(15 * time.Minute).Unix(),
-		"iss": "http://localhost:8080",
-		"sub": data,
-	})
-
-	tokenString, err := token.SignedString(i.key)
+// CheckKeys reports whether the issuer can still sign tokens.
+func (i *Issuer) CheckKeys() error {
+	_, _, err := i.keyset.SigningKey()
+	return err
+}
+
+func (i *Issuer) Token(authID uint64) (string, error) {
+	jti, err := randomString(16)
 	if err != nil {
-		return "", fmt.Errorf("unable to sign token: %w", err)
+		return "", fmt.Errorf("unable to generate token id: %w", err)
 	}
 
-	err = i.cache.Set(authID, now)
+	now := time.Now()
+	token := jwt.NewWithClaims(&jwt.SigningMethodEd25519{}, NewClaims(authID, jwt.RegisteredClaims{
+		ID:        jti,
+		Audience:  jwt.ClaimStrings{i.opts.Audience},
+		NotBefore: jwt.NewNumericDate(now),
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(now.Add(i.opts.TTL)),
+		Issuer:    i.opts.Issuer,
+	}))
+
+	kid, key, err := i.keyset.SigningKey()
 	if err != nil {
 		return "", err
 	}
+	token.Header["kid"] = kid
+
+	tokenString, err := token.SignedString(key)
+	if err != nil {
+		return "", fmt.Errorf("unable to sign token: %w", err)
+	}
 
 	return tokenString, nil
 }
+
+func (i *Issuer) Revoke(token *jwt.Token) error {
+	claims, err := TokenClaims(token)
+	if err != nil {
+		return err
+	}
+
+	if claims.ID == "" || claims.ExpiresAt == nil {
+		return errors.New("invalid token claims")
+	}
+
+	return i.cache.Revoke(claims.ID, claims.ExpiresAt.Add(i.opts.Leeway))
+}
+
+// RevokeAll moves the iat watermark past every token issued so far and drops
+// all refresh tokens of the user.
+func (i *Issuer) RevokeAll(authID uint64) error {
+	return RevokeSessions(i.cache, i.refreshStore, authID)
+}
+
+// RevokeSessions invalidates every access and refresh token of authID held
+// in the given stores. It is what RevokeAll does, for tools that share the
+// stores but have no Issuer.
+func RevokeSessions(cache Cache, refreshStore RefreshStore, authID uint64) error {
+	if err := cache.Set(authID, time.Now().Add(time.Second)); err != nil {
+		return err
+	}
+
+	return refreshStore.RevokeUser(authID)
+}
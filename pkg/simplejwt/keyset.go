@@ -0,0 +1,178 @@
+package simplejwt
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Keyset holds one active signing key and any number of verification keys,
+// all addressed by their RFC 7638 thumbprint used as the kid header.
+type Keyset struct {
+	signingKID string
+	signingKey ed25519.PrivateKey
+	publicKeys map[string]ed25519.PublicKey
+	kids       []string
+}
+
+func NewKeyset() *Keyset {
+	return &Keyset{
+		publicKeys: map[string]ed25519.PublicKey{},
+	}
+}
+
+// LoadKeyset reads the active private key (may be empty for verify-only
+// services) and additional public keys kept around during rotation.
+func LoadKeyset(signingKeyPath string, publicKeyPaths ...string) (*Keyset, error) {
+	ks := NewKeyset()
+
+	if signingKeyPath != "" {
+		keyBytes, err := os.ReadFile(signingKeyPath)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read private key file: %w", err)
+		}
+
+		key, err := jwt.ParseEdPrivateKeyFromPEM(keyBytes)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse as ed private key: %w", err)
+		}
+
+		edKey, ok := key.(ed25519.PrivateKey)
+		if !ok {
+			return nil, errors.New("private key is not an ed25519 key")
+		}
+
+		if err := ks.SetSigningKey(edKey); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, path := range publicKeyPaths {
+		keyBytes, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read public key file: %w", err)
+		}
+
+		key, err := jwt.ParseEdPublicKeyFromPEM(keyBytes)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse as ed public key: %w", err)
+		}
+
+		edKey, ok := key.(ed25519.PublicKey)
+		if !ok {
+			return nil, errors.New("public key is not an ed25519 key")
+		}
+
+		ks.AddPublicKey(edKey)
+	}
+
+	if len(ks.kids) == 0 {
+		return nil, errors.New("keyset is empty")
+	}
+
+	return ks, nil
+}
+
+func (ks *Keyset) SetSigningKey(key ed25519.PrivateKey) error {
+	pub, ok := key.Public().(ed25519.PublicKey)
+	if !ok {
+		return errors.New("unexpected public key type")
+	}
+
+	ks.signingKID = ks.AddPublicKey(pub)
+	ks.signingKey = key
+
+	return nil
+}
+
+func (ks *Keyset) AddPublicKey(key ed25519.PublicKey) string {
+	kid := Thumbprint(key)
+	if _, ok := ks.publicKeys[kid]; !ok {
+		ks.publicKeys[kid] = key
+		ks.kids = append(ks.kids, kid)
+	}
+
+	return kid
+}
+
+func (ks *Keyset) SigningKey() (string, ed25519.PrivateKey, error) {
+	if ks.signingKey == nil {
+		return "", nil, errors.New("keyset has no signing key")
+	}
+
+	return ks.signingKID, ks.signingKey, nil
+}
+
+func (ks *Keyset) PublicKey(kid string) (ed25519.PublicKey, bool) {
+	key, ok := ks.publicKeys[kid]
+	return key, ok
+}
+
+func Thumbprint(key ed25519.PublicKey) string {
+	// members in lexicographic order as required by RFC 7638
+	canonical := `{"crv":"Ed25519","kty":"OKP","x":"` +
+		base64.RawURLEncoding.EncodeToString(key) + `"}`
+	sum := sha256.Sum256([]byte(canonical))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+type JWK struct {
+	Kty string `json:"kty"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+func (ks *Keyset) JWKS() JWKS {
+	set := JWKS{Keys: make([]JWK, 0, len(ks.kids))}
+	for _, kid := range ks.kids {
+		set.Keys = append(set.Keys, JWK{
+			Kty: "OKP",
+			Crv: "Ed25519",
+			X:   base64.RawURLEncoding.EncodeToString(ks.publicKeys[kid]),
+			Kid: kid,
+			Use: "sig",
+			Alg: "EdDSA",
+		})
+	}
+
+	return set
+}
+
+// GenerateKeyPair creates an Ed25519 key pair as PEM blocks in the formats
+// LoadKeyset reads: PKCS #8 for the private key and PKIX for the public key.
+func GenerateKeyPair() (privatePEM, publicPEM []byte, err error) {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	privateDER, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	publicDER, err := x509.MarshalPKIXPublicKey(public)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	privatePEM = pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateDER})
+	publicPEM = pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER})
+
+	return privatePEM, publicPEM, nil
+}
@@ -1,54 +1,171 @@
 package simplejwt
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strings"
+	"time"
 
-	"github.com/golang-jwt/jwt/v5"
+	"github.com/askerdev/realworld-clone-go/internal/domain/entity"
+	"github.com/askerdev/realworld-clone-go/pkg/requestlog"
 )
 
+var ErrUserNotFound = errors.New("user not found")
+
+type UserLoader interface {
+	LoadUser(ctx context.Context, id uint64) (*entity.User, error)
+}
+
 type Middleware struct {
 	validator *Validator
+	users     UserLoader
+	pats      PersonalAccessTokenStore
 }
 
-func NewMiddleware(validator *Validator) *Middleware {
+// NewMiddleware builds the auth middleware. pats may be nil, in which case
+// personal access tokens are rejected like any other invalid token.
+func NewMiddleware(validator *Validator, users UserLoader, pats PersonalAccessTokenStore) *Middleware {
 	return &Middleware{
 		validator: validator,
+		users:     users,
+		pats:      pats,
 	}
 }
 
 func (m *Middleware) HandleHTTP(h http.Handler) http.Handler {
+	return m.handle(h, false)
+}
+
+// HandleHTTPOptional lets anonymous requests through and authenticates the
+// rest, so a missing header is fine but a malformed or invalid one is not.
+func (m *Middleware) HandleHTTPOptional(h http.Handler) http.Handler {
+	return m.handle(h, true)
+}
+
+// RequireScope only lets personal access tokens through when they carry the
+// scope. Requests authenticated with a JWT are not limited by scopes.
+func (m *Middleware) RequireScope(scope string, h http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		token, err := m.getHeaderToken(r.Header)
-		if err != nil {
-			w.WriteHeader(http.StatusUnauthorized)
-			json.NewEncoder(w).Encode(map[string]any{
-				"statusCode": http.StatusUnauthorized,
-				"message":    err.Error(),
-			})
+		pat := ContextPersonalAccessToken(r.Context())
+		if pat != nil && !pat.HasScope(scope) {
+			writeError(w, http.StatusForbidden, fmt.Sprintf("token lacks scope %q", scope))
 			return
 		}
 
-		ctx := ContextWithToken(r.Context(), token)
+		h.ServeHTTP(w, r)
+	})
+}
+
+func (m *Middleware) handle(h http.Handler, optional bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if optional && r.Header.Get("Authorization") == "" {
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		ctx, err := m.authenticate(r.Context(), r.Header)
+		if err != nil {
+			var authErr *authError
+			if !errors.As(err, &authErr) {
+				requestlog.FromContext(r.Context()).Error(err.Error())
+				writeError(w, http.StatusInternalServerError, "internal server error")
+				return
+			}
+			writeError(w, http.StatusUnauthorized, err.Error())
+			return
+		}
 
 		h.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
-func (m *Middleware) getHeaderToken(header http.Header) (*jwt.Token, error) {
-	auth := header.Get("Authorization")
-	if len(auth) < 8 || !strings.HasPrefix(auth, "Token ") {
-		return nil, fmt.Errorf("invalid header")
+type authError struct {
+	err error
+}
+
+func (e *authError) Error() string {
+	return e.err.Error()
+}
+
+func (e *authError) Unwrap() error {
+	return e.err
+}
+
+func (m *Middleware) authenticate(ctx context.Context, header http.Header) (context.Context, error) {
+	credentials, ok := m.headerCredentials(header)
+	if !ok {
+		return nil, &authError{errors.New("invalid header")}
 	}
 
-	tokenString := strings.TrimPrefix(auth, "Token ")
+	var userID uint64
+	if m.pats != nil && IsPersonalAccessToken(credentials) {
+		pat, err := m.pats.LookupPersonalAccessToken(ctx, HashPersonalAccessToken(credentials))
+		if err != nil {
+			if errors.Is(err, ErrInvalidPersonalAccessToken) {
+				return nil, &authError{err}
+			}
+			return nil, fmt.Errorf("unable to look up personal access token: %w", err)
+		}
+
+		if pat.ExpiresAt != nil && time.Now().After(*pat.ExpiresAt) {
+			return nil, &authError{ErrInvalidPersonalAccessToken}
+		}
 
-	token, err := m.validator.Validate(tokenString)
+		userID = pat.UserID
+		ctx = ContextWithPersonalAccessToken(ctx, pat)
+	} else {
+		token, err := m.validator.Validate(credentials)
+		if err != nil {
+			return nil, &authError{fmt.Errorf("invalid token: %w", err)}
+		}
+
+		claims, err := TokenClaims(token)
+		if err != nil {
+			return nil, &authError{err}
+		}
+
+		userID, err = claims.SubjectID()
+		if err != nil {
+			return nil, &authError{err}
+		}
+
+		ctx = ContextWithToken(ctx, token)
+	}
+
+	user, err := m.users.LoadUser(ctx, userID)
 	if err != nil {
-		return nil, fmt.Errorf("invalid token: %w", err)
+		if errors.Is(err, ErrUserNotFound) {
+			return nil, &authError{err}
+		}
+		return nil, fmt.Errorf("unable to load user: %w", err)
+	}
+
+	return ContextWithUser(ctx, user), nil
+}
+
+func (m *Middleware) headerCredentials(header http.Header) (string, bool) {
+	scheme, credentials, ok := strings.Cut(header.Get("Authorization"), " ")
+	if !ok || credentials == "" {
+		return "", false
 	}
 
-	return token, nil
+	for _, accepted := range m.validator.opts.Schemes {
+		if strings.EqualFold(scheme, accepted) {
+			return strings.TrimSpace(credentials), true
+		}
+	}
+
+	return "", false
+}
+
+func writeError(w http.ResponseWriter, statusCode int, message string) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(map[string]any{
+		"statusCode": statusCode,
+		"message":    message,
+	})
 }
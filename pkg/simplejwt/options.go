@@ -0,0 +1,47 @@
+package simplejwt
+
+import "time"
+
+type Options struct {
+	// Issuer is stamped into the iss claim and required on validation.
+	Issuer string
+	// Audience is stamped into the aud claim and required on validation.
+	Audience string
+	// TTL is the access token lifetime.
+	TTL time.Duration
+	// Leeway is the allowed clock skew for exp, nbf and iat checks.
+	Leeway time.Duration
+	// Schemes are the accepted Authorization header schemes.
+	Schemes []string
+}
+
+func DefaultOptions() Options {
+	return Options{
+		Issuer:   "http://localhost:8080",
+		Audience: "api",
+		TTL:      15 * time.Minute,
+		Leeway:   30 * time.Second,
+		Schemes:  []string{"Token", "Bearer"},
+	}
+}
+
+func (o Options) withDefaults() Options {
+	d := DefaultOptions()
+	if o.Issuer == "" {
+		o.Issuer = d.Issuer
+	}
+	if o.Audience == "" {
+		o.Audience = d.Audience
+	}
+	if o.TTL <= 0 {
+		o.TTL = d.TTL
+	}
+	if o.Leeway < 0 {
+		o.Leeway = 0
+	}
+	if len(o.Schemes) == 0 {
+		o.Schemes = d.Schemes
+	}
+
+	return o
+}
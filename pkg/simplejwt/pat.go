@@ -0,0 +1,47 @@
+package simplejwt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+const PersonalAccessTokenPrefix = "cpat_"
+
+var ErrInvalidPersonalAccessToken = errors.New("invalid personal access token")
+
+type PersonalAccessToken struct {
+	UserID    uint64
+	Scopes    []string
+	ExpiresAt *time.Time
+}
+
+// PersonalAccessTokenStore looks tokens up by the hash of their value and
+// returns ErrInvalidPersonalAccessToken for unknown ones.
+type PersonalAccessTokenStore interface {
+	LookupPersonalAccessToken(ctx context.Context, hash string) (*PersonalAccessToken, error)
+}
+
+func NewPersonalAccessToken() (string, string, error) {
+	token, hash, err := NewOpaqueToken(PersonalAccessTokenPrefix)
+	if err != nil {
+		return "", "", fmt.Errorf("unable to generate personal access token: %w", err)
+	}
+
+	return token, hash, nil
+}
+
+func HashPersonalAccessToken(token string) string {
+	return HashOpaqueToken(token)
+}
+
+func IsPersonalAccessToken(token string) bool {
+	return strings.HasPrefix(token, PersonalAccessTokenPrefix)
+}
+
+func (t *PersonalAccessToken) HasScope(scope string) bool {
+	return slices.Contains(t.Scopes, scope)
+}
@@ -0,0 +1,137 @@
+package simplejwt
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const refreshTokenTTL = 30 * 24 * time.Hour
+
+var (
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token reused")
+)
+
+type RefreshToken struct {
+	FamilyID  string
+	UserID    uint64
+	ExpiresAt time.Time
+	Used      bool
+}
+
+// RefreshStore keeps refresh tokens by the hash of their opaque value.
+// Use must atomically mark the token as used and return its previous state.
+// Get returns the token without touching it.
+type RefreshStore interface {
+	Save(hash string, token RefreshToken) error
+	Get(hash string) (RefreshToken, bool, error)
+	Use(hash string) (RefreshToken, bool, error)
+	RevokeFamily(familyID string) error
+	RevokeUser(userID uint64) error
+}
+
+func randomString(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// NewOpaqueToken returns a random token with the given prefix together with
+// the hash it should be stored under.
+func NewOpaqueToken(prefix string) (string, string, error) {
+	secret, err := randomString(32)
+	if err != nil {
+		return "", "", err
+	}
+
+	token := prefix + secret
+	return token, hashToken(token), nil
+}
+
+func HashOpaqueToken(token string) string {
+	return hashToken(token)
+}
+
+func (i *Issuer) issueRefreshToken(userID uint64, familyID string) (string, error) {
+	token, err := randomString(32)
+	if err != nil {
+		return "", fmt.Errorf("unable to generate refresh token: %w", err)
+	}
+
+	err = i.refreshStore.Save(hashToken(token), RefreshToken{
+		FamilyID:  familyID,
+		UserID:    userID,
+		ExpiresAt: time.Now().Add(refreshTokenTTL),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+func (i *Issuer) RefreshToken(authID uint64) (string, error) {
+	familyID, err := randomString(16)
+	if err != nil {
+		return "", fmt.Errorf("unable to generate token family: %w", err)
+	}
+
+	return i.issueRefreshToken(authID, familyID)
+}
+
+// Rotate exchanges a refresh token for a new one from the same family.
+// Presenting an already used token revokes the whole family.
+func (i *Issuer) Rotate(refreshToken string) (uint64, string, error) {
+	prev, ok, err := i.refreshStore.Use(hashToken(refreshToken))
+	if err != nil {
+		return 0, "", err
+	}
+	if !ok {
+		return 0, "", ErrInvalidRefreshToken
+	}
+
+	if prev.Used {
+		if err := i.refreshStore.RevokeFamily(prev.FamilyID); err != nil {
+			return 0, "", err
+		}
+		return 0, "", ErrRefreshTokenReused
+	}
+
+	if time.Now().After(prev.ExpiresAt) {
+		return 0, "", ErrInvalidRefreshToken
+	}
+
+	next, err := i.issueRefreshToken(prev.UserID, prev.FamilyID)
+	if err != nil {
+		return 0, "", err
+	}
+
+	return prev.UserID, next, nil
+}
+
+// RevokeRefreshToken revokes the family of refreshToken. Tokens that
+// don't belong to authID are left alone.
+func (i *Issuer) RevokeRefreshToken(authID uint64, refreshToken string) error {
+	token, ok, err := i.refreshStore.Get(hashToken(refreshToken))
+	if err != nil {
+		return err
+	}
+	if !ok || token.UserID != authID {
+		return nil
+	}
+
+	return i.refreshStore.RevokeFamily(token.FamilyID)
+}
@@ -1,70 +1,90 @@
 package simplejwt
 
 import (
-	"crypto"
 	"errors"
 	"fmt"
-	"os"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
 type Validator struct {
-	key   crypto.PublicKey
-	cache Cache
+	keyset *Keyset
+	cache  Cache
+	opts   Options
 }
 
-func NewValidator(publicKeyPath string, cache Cache) (*Validator, error) {
-	keyBytes, err := os.ReadFile(publicKeyPath)
-	if err != nil {
-		return nil, fmt.Errorf("unable to read public key file: %w", err)
-	}
-
-	key, err := jwt.ParseEdPublicKeyFromPEM(keyBytes)
-	if err != nil {
-		return nil, fmt.Errorf("unable to parse as ed public key: %w", err)
+func NewValidator(keyset *Keyset, cache Cache, opts Options) (*Validator, error) {
+	if len(keyset.kids) == 0 {
+		return nil, errors.New("keyset is empty")
 	}
 
 	return &Validator{
-		key:   key,
-		cache: cache,
+		keyset: keyset,
+		cache:  cache,
+		opts:   opts.withDefaults(),
 	}, nil
 }
 
+func (v *Validator) JWKS() JWKS {
+	return v.keyset.JWKS()
+}
+
 func (v *Validator) Validate(tokenString string) (*jwt.Token, error) {
-	token, err := jwt.Parse(
+	token, err := jwt.ParseWithClaims(
 		tokenString,
+		&Claims[uint64]{},
 		func(t *jwt.Token) (interface{}, error) {
 			if _, ok := t.Method.(*jwt.SigningMethodEd25519); !ok {
 				return nil, fmt.Errorf("unexpected singing method: %v", t.Header["alg"])
 			}
-			return v.key, nil
-		})
+
+			kid, ok := t.Header["kid"].(string)
+			if !ok {
+				// tokens issued before key rotation support carry no kid
+				kid = v.keyset.signingKID
+			}
+
+			key, ok := v.keyset.PublicKey(kid)
+			if !ok {
+				return nil, fmt.Errorf("unknown key id: %v", t.Header["kid"])
+			}
+			return key, nil
+		},
+		jwt.WithIssuer(v.opts.Issuer),
+		jwt.WithAudience(v.opts.Audience),
+		jwt.WithLeeway(v.opts.Leeway),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+	)
 	if err != nil {
 		return nil, fmt.Errorf("unable to parse token string: %w", err)
 	}
 
-	iAt, err := token.Claims.GetIssuedAt()
+	claims, err := TokenClaims(token)
 	if err != nil {
 		return nil, err
 	}
 
-	claims := token.Claims.(jwt.MapClaims)
-	sub, ok := claims["sub"].(map[string]any)
-	if !ok {
-		return nil, errors.New("invalid token claims")
+	id, err := claims.SubjectID()
+	if err != nil {
+		return nil, err
 	}
-	id, ok := sub["id"].(float64)
-	if !ok {
+
+	if claims.ID == "" || claims.IssuedAt == nil {
 		return nil, errors.New("invalid token claims")
 	}
 
-	prevIAt, ok := v.cache.Get(uint64(id))
-	if !ok {
-		return nil, errors.New("invalid iat")
+	if v.cache.Revoked(claims.ID) {
+		return nil, errors.New("token revoked")
 	}
 
-	if iAt.Unix() < prevIAt.Unix() {
+	// Only RevokeSessions moves the watermark, so a user without one has
+	// nothing revoked. Like Revoked, fail closed when it can't be read.
+	prevIAt, ok, err := v.cache.Get(id)
+	if err != nil {
+		return nil, fmt.Errorf("unable to check iat: %w", err)
+	}
+	if ok && claims.IssuedAt.Unix() < prevIAt.Unix() {
 		return nil, errors.New("invalid iat")
 	}
 
@@ -0,0 +1,132 @@
+package totp
+
+import (
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA-1 seed of RFC 6238 appendix B, "12345678901234567890",
+// in base32.
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+// The RFC lists 8-digit codes; these are their last six digits.
+var rfcVectors = []struct {
+	unix int64
+	code string
+}{
+	{59, "287082"},
+	{1111111109, "081804"},
+	{1111111111, "050471"},
+	{1234567890, "005924"},
+	{2000000000, "279037"},
+	{20000000000, "353130"},
+}
+
+func TestCodeAt(t *testing.T) {
+	for _, v := range rfcVectors {
+		got, err := CodeAt(rfcSecret, Step(time.Unix(v.unix, 0)))
+		if err != nil {
+			t.Fatalf("CodeAt(%d): %v", v.unix, err)
+		}
+		if got != v.code {
+			t.Errorf("CodeAt(%d) = %s, want %s", v.unix, got, v.code)
+		}
+	}
+}
+
+func TestCodeAtLowercaseSecret(t *testing.T) {
+	got, err := CodeAt("gezdgnbvgy3tqojqgezdgnbvgy3tqojq", Step(time.Unix(59, 0)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "287082" {
+		t.Errorf("got %s, want 287082", got)
+	}
+}
+
+func TestCodeAtInvalidSecret(t *testing.T) {
+	if _, err := CodeAt("not base32!", 1); err == nil {
+		t.Error("expected an error for an invalid secret")
+	}
+}
+
+func TestValidateSkew(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	current := Step(now)
+
+	tests := []struct {
+		name  string
+		step  int64
+		skew  int64
+		valid bool
+	}{
+		{"current step", current, 0, true},
+		{"previous step without skew", current - 1, 0, false},
+		{"previous step", current - 1, 1, true},
+		{"next step", current + 1, 1, true},
+		{"two steps back", current - 2, 1, false},
+		{"two steps ahead", current + 2, 1, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, err := CodeAt(rfcSecret, tt.step)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			step, ok := Validate(rfcSecret, code, now, tt.skew)
+			if ok != tt.valid {
+				t.Fatalf("Validate = %v, want %v", ok, tt.valid)
+			}
+			// The matched step is what callers record to refuse replays,
+			// so it must be the code's own step, not the current one.
+			if ok && step != tt.step {
+				t.Errorf("Validate step = %d, want %d", step, tt.step)
+			}
+		})
+	}
+}
+
+func TestValidateStepReplay(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+
+	first, ok := Validate(rfcSecret, "005924", now, 1)
+	if !ok {
+		t.Fatal("expected the code to be valid")
+	}
+
+	// Within the skew window the same code keeps validating to the same
+	// step, which is what lets storage refuse it the second time.
+	again, ok := Validate(rfcSecret, "005924", now.Add(Period), 1)
+	if !ok || again != first {
+		t.Errorf("Validate = (%d, %v), want (%d, true)", again, ok, first)
+	}
+
+	// Past the window it no longer validates at all.
+	if _, ok := Validate(rfcSecret, "005924", now.Add(2*Period), 1); ok {
+		t.Error("expected the code to expire after the skew window")
+	}
+}
+
+func TestValidateFormat(t *testing.T) {
+	now := time.Unix(59, 0)
+
+	tests := []struct {
+		code  string
+		valid bool
+	}{
+		{"287082", true},
+		{"287 082", true},
+		{"28708", false},
+		{"2870820", false},
+		{"", false},
+		{"287083", false},
+	}
+
+	for _, tt := range tests {
+		if _, ok := Validate(rfcSecret, tt.code, now, 0); ok != tt.valid {
+			t.Errorf("Validate(%q) = %v, want %v", tt.code, ok, tt.valid)
+		}
+	}
+}